@@ -4,53 +4,241 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 )
 
 // 操作阿里网盘的SDK客户端
 type Drive struct {
 	RefreshToken string
-	HttpClient   *http.Client
+	// refresh token的持久化存储，可选
+	//
+	// 设置后优先使用其中保存的refresh token，并且每次轮换后都会保存新的refresh token
+	RefreshTokenStore RefreshTokenStore
+	HttpClient        *http.Client
+	// 接口地址配置，可选，默认为阿里云盘官方服务地址
+	Endpoint Endpoint
+	// 请求失败时的重试策略，可选，为空则使用DefaultRetryPolicy
+	RetryPolicy *RetryPolicy
+	// Token管理器，可选
+	//
+	// 为空则Init时使用RefreshToken（设置了OAuth时使用OAuth配置）创建保活的Token管理器，
+	// 需要集中管理token时可以注入NewStaticTokenManager或者自定义的实现
+	TokenManager TokenManager
+	// 开放平台OAuth配置，可选
+	//
+	// 设置后通过开放平台OAuth获取accesstoken，并且使用开放平台接口，
+	// OAuth配置中没有refresh token时使用RefreshToken
+	OAuth *OAuthConfig
+	// 设备名称，创建设备session时使用，可选，为空则使用DefaultDeviceName
+	DeviceName string
+	// 设备型号，创建设备session时使用，可选，为空则使用DefaultModelName
+	ModelName string
+	// 设备Id，可选，为空则Init时根据用户Id生成，自定义签名管理器时需要用到
+	//
+	// 默认的设备Id对同一账号是固定的，同一账号下同时运行多个程序时，
+	// 需要为每个程序设置不同的设备Id，否则会互相把对方挤下线
+	DeviceId string
+	// 签名管理器，可选，为空则Init时使用NewSignatureManager创建并在后台保活session，
+	// 使用开放平台接口时不需要签名
+	SignatureManager SignatureManager
+	// 调用拦截器，可选，每次接口调用以及上传/下载数据的每次尝试都会依次经过
+	Interceptors []Interceptor
+	// 日志，可选，为空则不输出日志
+	//
+	// 会记录token刷新、签名session、请求失败及重试等信息，token会被隐藏
+	Logger *slog.Logger
+	// 监控指标收集，可选，为空则不收集
+	//
+	// 可以使用NewExpvarMetrics或者自定义的实现
+	Metrics Metrics
+	// 接口调用的客户端限流，可选，为空则不限流
+	ApiRateLimit *RateLimit
+	// 上传/下载数据的客户端限流，按字节数限制传输速度，可选，为空则不限流
+	TransferRateLimit *RateLimit
 
-	ctx    context.Context
-	cancel context.CancelFunc
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
 
-	tokenManager     *keepAliveTokenManager
-	signatureManager *signatureManager
+	// Init时创建的保活Token管理器，注入TokenManager时为空
+	keepAlive         *keepAliveTokenManager
+	keepAliveInterval time.Duration
+	// Init时创建的签名管理器，注入SignatureManager时为空
+	signature *signatureManager
 
-	driveId  string
-	userId   string
-	deviceId string
+	apiLimiter      *rateLimiter
+	transferLimiter *rateLimiter
+
+	driveId         string
+	resourceDriveId string
+	backupDriveId   string
+	userId          string
+}
+
+const (
+	// 默认的设备名称
+	DefaultDeviceName = "Chrome浏览器"
+	// 默认的设备型号
+	DefaultModelName = "Mac OS网页版"
+)
+
+// 创建并初始化一个SDK客户端
+//
+// ctx只用于初始化过程（如获取用户信息），取消后初始化会失败返回；
+// 客户端使用完之后需要调用Close释放后台任务
+func New(ctx context.Context, opts ...Option) (*Drive, error) {
+	c := new(Drive)
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.init(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 // 初始化参数
+//
+// Deprecated: 使用New创建客户端
 func (c *Drive) Init() error {
+	return c.init(context.Background())
+}
+
+func (c *Drive) init(ctx context.Context) error {
 	if c.HttpClient == nil {
 		c.HttpClient = http.DefaultClient
 	}
+	if c.DeviceName == "" {
+		c.DeviceName = DefaultDeviceName
+	}
+	if c.ModelName == "" {
+		c.ModelName = DefaultModelName
+	}
+	if c.keepAliveInterval <= 0 {
+		c.keepAliveInterval = time.Second * 10
+	}
+	c.apiLimiter = newRateLimiter(c.ApiRateLimit)
+	c.transferLimiter = newRateLimiter(c.TransferRateLimit)
+	if c.OAuth != nil {
+		c.Endpoint.Open = true
+	}
 	c.ctx, c.cancel = context.WithCancel(context.Background())
-	c.tokenManager = NewKeepAliveTokenManager(NewRefreshTokenManager(c, c.RefreshToken))
-	c.tokenManager.KeepAlive(c.ctx, time.Second*10)
+	if c.TokenManager == nil {
+		tokenManager, err := c.newTokenManager(ctx)
+		if err != nil {
+			c.cancel()
+			return err
+		}
+		c.keepAlive = NewKeepAliveTokenManager(tokenManager)
+		c.keepAlive.logger = c.logger()
+		c.keepAlive.KeepAlive(c.ctx, c.keepAliveInterval)
+		c.TokenManager = c.keepAlive
+	}
 
-	resp, err := c.DoGetUserInfoRequest(c.ctx, GetUserInfoRequest{})
+	resp, err := c.DoGetUserInfoRequest(ctx, GetUserInfoRequest{})
 	if err != nil {
-		c.Destory()
+		c.Close()
 		return err
 	}
 
-	c.driveId = resp.DefaultDriveID
+	if c.driveId == "" {
+		c.driveId = resp.DefaultDriveID
+	}
+	c.resourceDriveId = resp.ResourceDriveId
+	c.backupDriveId = resp.BackupDriveId
 	c.userId = resp.UserID
-	hasher := sha256.New()
-	hasher.Write([]byte(c.userId))
-	c.deviceId = hex.EncodeToString(hasher.Sum(nil))
+	if c.DeviceId == "" {
+		hasher := sha256.New()
+		hasher.Write([]byte(c.userId))
+		c.DeviceId = hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	if c.SignatureManager == nil && !c.Endpoint.Open {
+		c.signature = NewSignatureManager(c)
+		c.signature.KeepAlive(c.ctx, c.keepAliveInterval)
+		c.SignatureManager = c.signature
+	}
+
+	return nil
+}
 
-	c.signatureManager = NewSignatureManager(c)
+// 创建默认的Token管理器，设置了OAuth时使用开放平台OAuth，否则使用RefreshToken
+func (c *Drive) newTokenManager(ctx context.Context) (TokenManager, error) {
+	if c.OAuth != nil {
+		config := *c.OAuth
+		if config.RefreshToken == "" {
+			config.RefreshToken = c.RefreshToken
+		}
+		if c.RefreshTokenStore != nil {
+			return NewStoredOAuthTokenManager(ctx, c, config, c.RefreshTokenStore)
+		}
+		return NewOAuthTokenManager(c, config), nil
+	}
+	if c.RefreshTokenStore != nil {
+		return NewStoredRefreshTokenManager(ctx, c, c.RefreshToken, c.RefreshTokenStore)
+	}
+	return NewRefreshTokenManager(c, c.RefreshToken), nil
+}
 
+// 关闭客户端，结束token保活等后台任务，可以重复调用
+func (c *Drive) Close() error {
+	c.closeOnce.Do(func() {
+		if c.cancel != nil {
+			c.cancel()
+		}
+		if c.keepAlive != nil {
+			c.keepAlive.WaitStop()
+		}
+		if c.signature != nil {
+			c.signature.WaitStop()
+		}
+	})
 	return nil
 }
 
+// Deprecated: 使用Close
 func (c *Drive) Destory() {
-	c.cancel()
-	c.tokenManager.WaitStop()
+	c.Close()
+}
+
+// 当前使用的网盘Id
+func (c *Drive) DriveId() string {
+	return c.driveId
+}
+
+// 用户的资源库网盘Id，账号没有资源库时为空
+func (c *Drive) ResourceDriveId() string {
+	return c.resourceDriveId
+}
+
+// 用户的备份盘网盘Id，账号没有备份盘时为空
+func (c *Drive) BackupDriveId() string {
+	return c.backupDriveId
+}
+
+// 请求使用的网盘Id，依次取第一个不为空的driveIds，都为空则使用当前的网盘Id
+func (c *Drive) requestDriveId(driveIds ...string) string {
+	for _, driveId := range driveIds {
+		if driveId != "" {
+			return driveId
+		}
+	}
+	return c.driveId
+}
+
+// 当前登录的用户Id
+func (c *Drive) UserId() string {
+	return c.userId
+}
+
+// 当前签名使用的nonce，签名管理器没有实现NonceSignatureManager时ok为false
+func (c *Drive) SignatureNonce() (nonce int, ok bool) {
+	signatureManager, ok := c.SignatureManager.(NonceSignatureManager)
+	if !ok {
+		return 0, false
+	}
+	return signatureManager.Nonce(), true
 }
@@ -0,0 +1,273 @@
+package aliyundrive_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+	"github.com/xbugio/aliyundrive-go-sdk/aliyundrivetest"
+)
+
+// 创建模拟服务以及连接到它的Drive，测试结束时关闭
+func newTestDrive(t *testing.T, opts ...aliyundrive.Option) (*aliyundrivetest.Server, *aliyundrive.Drive) {
+	t.Helper()
+	srv := aliyundrivetest.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := srv.NewDrive(opts...)
+	if err != nil {
+		t.Fatalf("new drive: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return srv, c
+}
+
+// 统计每个调用名实际发送请求次数的拦截器
+func countCalls(counts map[string]*atomic.Int32) aliyundrive.Interceptor {
+	return func(ctx context.Context, call *aliyundrive.Call, next aliyundrive.Handler) error {
+		if n, ok := counts[call.Operation]; ok {
+			n.Add(1)
+		}
+		return next(ctx, call)
+	}
+}
+
+// 分片上传data到parentFileId下的name
+func uploadFile(t *testing.T, c *aliyundrive.Drive, parentFileId string, name string, data []byte, chunkSize uint64) *aliyundrive.CompleteUploadFileResponse {
+	t.Helper()
+	ctx := context.Background()
+	created, err := c.DoCreateFileRequest(ctx, aliyundrive.CreateFileRequest{
+		Name:         name,
+		ParentFileId: parentFileId,
+		Size:         uint64(len(data)),
+		ChunkSize:    chunkSize,
+	})
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	for i, part := range created.PartInfoList {
+		start := uint64(i) * chunkSize
+		end := min(start+chunkSize, uint64(len(data)))
+		_, err := c.DoUploadFileRequest(ctx, aliyundrive.UploadFileRequest{
+			Url:  part.UploadUrl,
+			File: bytes.NewReader(data[start:end]),
+		})
+		if err != nil {
+			t.Fatalf("upload part %d: %v", part.PartNumber, err)
+		}
+	}
+	completed, err := c.DoCompleteUploadFileRequest(ctx, aliyundrive.CompleteUploadFileRequest{
+		FileId:   created.FileId,
+		UploadId: created.UploadId,
+	})
+	if err != nil {
+		t.Fatalf("complete upload: %v", err)
+	}
+	return completed
+}
+
+// 下载文件的全部内容
+func downloadFile(t *testing.T, c *aliyundrive.Drive, fileId string) []byte {
+	t.Helper()
+	ctx := context.Background()
+	url, err := c.DoGetDownloadUrlRequest(ctx, aliyundrive.GetDownloadUrlRequest{FileId: fileId})
+	if err != nil {
+		t.Fatalf("get download url: %v", err)
+	}
+	resp, err := c.DoDownloadFileRequest(ctx, aliyundrive.DownloadFileRequest{Url: url.Url})
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	defer resp.Reader.Close()
+	data, err := io.ReadAll(resp.Reader)
+	if err != nil {
+		t.Fatalf("read download: %v", err)
+	}
+	return data
+}
+
+func TestUploadDownloadRoundTrip(t *testing.T) {
+	srv, c := newTestDrive(t)
+	ctx := context.Background()
+	dir := srv.AddFolder(aliyundrive.RootFileId, "docs")
+
+	data := bytes.Repeat([]byte("0123456789"), 300)
+	completed := uploadFile(t, c, dir.FileId, "a.bin", data, 1024)
+	if completed.Size != uint64(len(data)) {
+		t.Fatalf("completed size = %d, want %d", completed.Size, len(data))
+	}
+	if content, _ := srv.Content(completed.FileId); !bytes.Equal(content, data) {
+		t.Fatalf("server content has %d bytes, want %d", len(content), len(data))
+	}
+
+	list, err := c.DoListRequest(ctx, aliyundrive.ListRequest{ParentFileId: dir.FileId})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "a.bin" {
+		t.Fatalf("list items = %+v, want a.bin", list.Items)
+	}
+
+	item, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: completed.FileId})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if item.Name != "a.bin" || item.ParentFileId != dir.FileId || item.Size != uint64(len(data)) {
+		t.Fatalf("get item = %+v", item)
+	}
+
+	if got := downloadFile(t, c, completed.FileId); !bytes.Equal(got, data) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(data))
+	}
+
+	_, err = c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: "not-exist"})
+	if !errors.Is(err, aliyundrive.ErrNotFound) {
+		t.Fatalf("get missing file error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMoveAcrossDrives(t *testing.T) {
+	srv, c := newTestDrive(t)
+	ctx := context.Background()
+	if c.ResourceDriveId() != aliyundrivetest.ResourceDriveId {
+		t.Fatalf("resource drive Id = %q, want %q", c.ResourceDriveId(), aliyundrivetest.ResourceDriveId)
+	}
+	file := srv.AddFile(aliyundrive.RootFileId, "a.txt", []byte("hello"))
+	dir := srv.AddDriveFolder(c.ResourceDriveId(), aliyundrive.RootFileId, "docs")
+
+	_, err := c.DoMoveRequest(ctx, aliyundrive.MoveRequest{FileId: file.FileId, ToParentFileId: dir.FileId, ToDriveId: c.ResourceDriveId()})
+	if err != nil {
+		t.Fatalf("move to resource drive: %v", err)
+	}
+	list, err := c.DoListRequest(ctx, aliyundrive.ListRequest{ParentFileId: dir.FileId, DriveId: c.ResourceDriveId()})
+	if err != nil {
+		t.Fatalf("list resource drive: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].FileId != file.FileId || list.Items[0].DriveId != c.ResourceDriveId() {
+		t.Fatalf("resource drive items = %+v, want the moved a.txt", list.Items)
+	}
+	list, err = c.DoListRequest(ctx, aliyundrive.ListRequest{ParentFileId: aliyundrive.RootFileId})
+	if err != nil {
+		t.Fatalf("list default drive: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Fatalf("default drive items = %+v, want none after moving", list.Items)
+	}
+
+	// 不指定DriveId时使用默认网盘，找不到其他网盘中的文件
+	if _, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: file.FileId}); !errors.Is(err, aliyundrive.ErrNotFound) {
+		t.Fatalf("get moved file from default drive error = %v, want ErrNotFound", err)
+	}
+	item, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: file.FileId, DriveId: c.ResourceDriveId()})
+	if err != nil {
+		t.Fatalf("get moved file from resource drive: %v", err)
+	}
+	if item.ParentFileId != dir.FileId {
+		t.Fatalf("moved file parent = %s, want %s", item.ParentFileId, dir.FileId)
+	}
+}
+
+func TestInjectFault(t *testing.T) {
+	srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(aliyundrive.RetryPolicy{MaxAttempts: 1}))
+	ctx := context.Background()
+
+	srv.InjectFault("/v2/file/get", 1, 404, "NotFound.File")
+	_, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
+	var errResponse *aliyundrive.ErrorResponse
+	if !errors.As(err, &errResponse) || errResponse.StatusCode != 404 || !errors.Is(err, aliyundrive.ErrNotFound) {
+		t.Fatalf("injected fault error = %v, want 404 NotFound.File", err)
+	}
+	if _, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get after fault: %v", err)
+	}
+
+	file := srv.AddFile(aliyundrive.RootFileId, "a.txt", []byte("hello"))
+	srv.InjectFault("/download/", 1, 403, "AccessDenied")
+	url, err := c.DoGetDownloadUrlRequest(ctx, aliyundrive.GetDownloadUrlRequest{FileId: file.FileId})
+	if err != nil {
+		t.Fatalf("get download url: %v", err)
+	}
+	_, err = c.DoDownloadFileRequest(ctx, aliyundrive.DownloadFileRequest{Url: url.Url})
+	var ossError *aliyundrive.OssError
+	if !errors.As(err, &ossError) || ossError.Code != "AccessDenied" {
+		t.Fatalf("injected download fault error = %v, want OSS AccessDenied", err)
+	}
+}
+
+func TestDataUrlErrors(t *testing.T) {
+	srv, c := newTestDrive(t)
+	ctx := context.Background()
+	file := srv.AddFile(aliyundrive.RootFileId, "a.txt", []byte("hello"))
+
+	// 地址生成时就已经过期
+	srv.UrlTTL = -time.Minute
+	url, err := c.DoGetDownloadUrlRequest(ctx, aliyundrive.GetDownloadUrlRequest{FileId: file.FileId})
+	if err != nil {
+		t.Fatalf("get download url: %v", err)
+	}
+	_, err = c.DoDownloadFileRequest(ctx, aliyundrive.DownloadFileRequest{Url: url.Url})
+	if !errors.Is(err, aliyundrive.ErrUrlExpired) || errors.Is(err, aliyundrive.ErrForbidden) {
+		t.Fatalf("download from expired url error = %v, want ErrUrlExpired and not ErrForbidden", err)
+	}
+	created, err := c.DoCreateFileRequest(ctx, aliyundrive.CreateFileRequest{Name: "b.txt", ParentFileId: aliyundrive.RootFileId, Size: 5})
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	_, err = c.DoUploadFileRequest(ctx, aliyundrive.UploadFileRequest{Url: created.PartInfoList[0].UploadUrl, File: bytes.NewReader([]byte("hello"))})
+	if !errors.Is(err, aliyundrive.ErrUrlExpired) || errors.Is(err, aliyundrive.ErrForbidden) {
+		t.Fatalf("upload to expired url error = %v, want ErrUrlExpired and not ErrForbidden", err)
+	}
+
+	srv.UrlTTL = time.Minute
+	url, err = c.DoGetDownloadUrlRequest(ctx, aliyundrive.GetDownloadUrlRequest{FileId: file.FileId})
+	if err != nil {
+		t.Fatalf("get download url: %v", err)
+	}
+	srv.InjectFault("/download/", 1, 403, "AccessDenied")
+	_, err = c.DoDownloadFileRequest(ctx, aliyundrive.DownloadFileRequest{Url: url.Url})
+	if !errors.Is(err, aliyundrive.ErrForbidden) || errors.Is(err, aliyundrive.ErrUrlExpired) {
+		t.Fatalf("download denied error = %v, want ErrForbidden and not ErrUrlExpired", err)
+	}
+	srv.InjectFault("/download/", 1, 416, "InvalidRange")
+	_, err = c.DoDownloadFileRequest(ctx, aliyundrive.DownloadFileRequest{Url: url.Url, Header: http.Header{"Range": {"bytes=10-"}}})
+	if !errors.Is(err, aliyundrive.ErrInvalidRange) {
+		t.Fatalf("download with invalid range error = %v, want ErrInvalidRange", err)
+	}
+}
+
+func TestRevokeAccessTokens(t *testing.T) {
+	counts := map[string]*atomic.Int32{"token/refresh": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+	before := counts["token/refresh"].Load()
+
+	srv.RevokeAccessTokens()
+	if _, err := c.DoListRequest(ctx, aliyundrive.ListRequest{ParentFileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("list after revoking access tokens: %v", err)
+	}
+	if got := counts["token/refresh"].Load() - before; got != 1 {
+		t.Fatalf("token refreshed %d times, want 1", got)
+	}
+}
+
+func TestRevokeDeviceSessions(t *testing.T) {
+	counts := map[string]*atomic.Int32{"session/create": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+
+	if _, err := c.DoListRequest(ctx, aliyundrive.ListRequest{ParentFileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	srv.RevokeDeviceSessions()
+	if _, err := c.DoListRequest(ctx, aliyundrive.ListRequest{ParentFileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("list after revoking device sessions: %v", err)
+	}
+	if got := counts["session/create"].Load(); got != 2 {
+		t.Fatalf("session created %d times, want 2", got)
+	}
+}
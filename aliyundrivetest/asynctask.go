@@ -0,0 +1,81 @@
+package aliyundrivetest
+
+import (
+	"net/http"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 删除目录、清空回收站等操作创建的异步任务
+//
+// 操作本身立即生效，只是在被查询AsyncTaskPolls次之前一直处于执行中
+type asyncTask struct {
+	polls int
+	total int64
+	// 任务失败时的错误码和原因
+	errorCode    string
+	errorMessage string
+}
+
+// 让下一个创建的异步任务执行失败
+func (s *Server) FailNextAsyncTask(code string, message string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.asyncTaskFailure = &asyncTask{errorCode: code, errorMessage: message}
+}
+
+// 创建一个异步任务，total为任务涉及的文件数，调用时需要持有锁
+func (s *Server) newAsyncTask(total int64) string {
+	task := &asyncTask{total: total}
+	if s.asyncTaskFailure != nil {
+		task.errorCode, task.errorMessage = s.asyncTaskFailure.errorCode, s.asyncTaskFailure.errorMessage
+		s.asyncTaskFailure = nil
+	}
+	asyncTaskId := randomId()
+	s.asyncTasks[asyncTaskId] = task
+	return asyncTaskId
+}
+
+// 目录树中的文件/目录数，调用时需要持有锁
+func (s *Server) treeSize(item *aliyundrive.Item) int64 {
+	size := int64(1)
+	for _, child := range s.children(item.DriveId, item.FileId) {
+		size += s.treeSize(child)
+	}
+	return size
+}
+
+func (s *Server) handleGetAsyncTask(w http.ResponseWriter, r *http.Request) {
+	params := new(aliyundrive.GetAsyncTaskRequest)
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	task, ok := s.asyncTasks[params.AsyncTaskId]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound.AsyncTask", "The resource async_task cannot be found.")
+		return
+	}
+
+	result := aliyundrive.GetAsyncTaskResponse{
+		AsyncTaskId:  params.AsyncTaskId,
+		State:        aliyundrive.AsyncTaskStateSucceed,
+		TotalProcess: task.total,
+	}
+	switch {
+	case task.polls < s.AsyncTaskPolls:
+		result.State = aliyundrive.AsyncTaskStateRunning
+		result.ConsumedProcess = task.total * int64(task.polls) / int64(s.AsyncTaskPolls)
+		task.polls++
+	case task.errorCode != "":
+		result.State = aliyundrive.AsyncTaskStateFailed
+		result.ConsumedProcess = task.total
+		result.ErrorCode = task.errorCode
+		result.ErrorMessage = task.errorMessage
+	default:
+		result.ConsumedProcess = task.total
+	}
+	writeJSON(w, http.StatusOK, result)
+}
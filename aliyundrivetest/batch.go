@@ -0,0 +1,67 @@
+package aliyundrivetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 批量接口子请求路径对应的处理函数
+func (s *Server) batchHandler(url string) apiHandler {
+	switch url {
+	case "/file/move":
+		return s.handleMove
+	case "/file/update":
+		return s.handleUpdate
+	case "/recyclebin/trash":
+		return s.handleTrash
+	case "/file/delete":
+		return s.handleDelete
+	}
+	return nil
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		Requests []struct {
+			Id   string          `json:"id"`
+			Url  string          `json:"url"`
+			Body json.RawMessage `json:"body"`
+		} `json:"requests"`
+		Resource string `json:"resource"`
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+	if len(params.Requests) > aliyundrive.BatchLimitMax {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.Requests", "The input parameter requests is not valid. too many sub requests")
+		return
+	}
+
+	// 逐个交给对应的处理函数，子请求之间互不影响
+	responses := make([]*aliyundrive.BatchSubResponse, 0, len(params.Requests))
+	for _, request := range params.Requests {
+		recorder := httptest.NewRecorder()
+		handler := s.batchHandler(request.Url)
+		if handler == nil {
+			writeError(recorder, http.StatusNotFound, "NotFound.Api", "api not found: "+request.Url)
+		} else {
+			handler(recorder, httptest.NewRequest("POST", request.Url, bytes.NewReader(request.Body)))
+		}
+		body := bytes.TrimSpace(recorder.Body.Bytes())
+		if len(body) == 0 {
+			body = []byte("{}")
+		}
+		responses = append(responses, &aliyundrive.BatchSubResponse{
+			Id:     request.Id,
+			Status: recorder.Code,
+			Body:   body,
+		})
+	}
+	writeJSON(w, http.StatusOK, aliyundrive.BatchResponse{
+		Responses: responses,
+	})
+}
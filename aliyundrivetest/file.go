@@ -0,0 +1,985 @@
+package aliyundrivetest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 在默认网盘的指定目录下添加一个目录，返回创建的目录
+//
+// 父级目录不存在或者不是目录时会panic，仅用于准备测试数据
+func (s *Server) AddFolder(parentFileId string, name string) *aliyundrive.Item {
+	return s.AddDriveFolder(DriveId, parentFileId, name)
+}
+
+// 在指定网盘的指定目录下添加一个目录，返回创建的目录
+//
+// 父级目录不存在或者不是目录时会panic，仅用于准备测试数据
+func (s *Server) AddDriveFolder(driveId string, parentFileId string, name string) *aliyundrive.Item {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.mustFolder(driveId, parentFileId)
+	item := s.newItem(driveId, parentFileId, name, "folder")
+	s.items[item.FileId] = item
+	return copyItem(item)
+}
+
+// 在默认网盘的指定目录下添加一个文件，返回创建的文件
+//
+// 父级目录不存在或者不是目录时会panic，仅用于准备测试数据
+func (s *Server) AddFile(parentFileId string, name string, data []byte) *aliyundrive.Item {
+	return s.AddDriveFile(DriveId, parentFileId, name, data)
+}
+
+// 在指定网盘的指定目录下添加一个文件，返回创建的文件
+//
+// 父级目录不存在或者不是目录时会panic，仅用于准备测试数据
+func (s *Server) AddDriveFile(driveId string, parentFileId string, name string, data []byte) *aliyundrive.Item {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.mustFolder(driveId, parentFileId)
+	item := s.newItem(driveId, parentFileId, name, "file")
+	s.setContent(item, data)
+	s.items[item.FileId] = item
+	return copyItem(item)
+}
+
+// 获取文件的数据
+func (s *Server) Content(fileId string) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, ok := s.contents[fileId]
+	return data, ok
+}
+
+// 获取文件/目录，fileId为RootFileId时返回默认网盘的根目录
+func (s *Server) Item(fileId string) (*aliyundrive.Item, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.items[fileId]
+	if fileId == aliyundrive.RootFileId {
+		item, ok = s.roots[DriveId]
+	}
+	if !ok {
+		return nil, false
+	}
+	return copyItem(item), true
+}
+
+// 获取指定网盘中的文件/目录
+func (s *Server) item(driveId string, fileId string) (*aliyundrive.Item, bool) {
+	if fileId == aliyundrive.RootFileId {
+		item, ok := s.roots[driveId]
+		return item, ok
+	}
+	item, ok := s.items[fileId]
+	if !ok || item.DriveId != driveId {
+		return nil, false
+	}
+	return item, true
+}
+
+func (s *Server) mustFolder(driveId string, fileId string) {
+	parent, ok := s.item(driveId, fileId)
+	if !ok || parent.Type != "folder" {
+		panic("aliyundrivetest: folder not found: " + fileId)
+	}
+}
+
+func (s *Server) newItem(driveId string, parentFileId string, name string, typ string) *aliyundrive.Item {
+	now := time.Now()
+	return &aliyundrive.Item{
+		DriveId:      driveId,
+		FileId:       randomId(),
+		Name:         name,
+		ParentFileId: parentFileId,
+		Type:         typ,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Status:       "available",
+	}
+}
+
+func (s *Server) setContent(item *aliyundrive.Item, data []byte) {
+	sum := sha1.Sum(data)
+	item.Size = uint64(len(data))
+	item.ContentHash = strings.ToUpper(hex.EncodeToString(sum[:]))
+	item.ContentHashName = "sha1"
+	item.FileExtension = strings.TrimPrefix(path.Ext(item.Name), ".")
+	item.UpdatedAt = time.Now()
+	s.contents[item.FileId] = data
+}
+
+func copyItem(item *aliyundrive.Item) *aliyundrive.Item {
+	result := *item
+	return &result
+}
+
+func (s *Server) children(driveId string, parentFileId string) []*aliyundrive.Item {
+	var items []*aliyundrive.Item
+	for _, item := range s.items {
+		if item.DriveId == driveId && item.ParentFileId == parentFileId && !item.Trashed {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (s *Server) childByName(driveId string, parentFileId string, name string) *aliyundrive.Item {
+	for _, item := range s.children(driveId, parentFileId) {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// 生成auto_rename模式下不冲突的名字
+func (s *Server) availableName(driveId string, parentFileId string, name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; s.childByName(driveId, parentFileId, name) != nil; i++ {
+		name = base + "(" + strconv.Itoa(i) + ")" + ext
+	}
+	return name
+}
+
+// 判断fileId是否是ancestorId本身或者其子孙
+func (s *Server) isDescendant(driveId string, fileId string, ancestorId string) bool {
+	for fileId != "" {
+		if fileId == ancestorId {
+			return true
+		}
+		item, ok := s.item(driveId, fileId)
+		if !ok {
+			return false
+		}
+		fileId = item.ParentFileId
+	}
+	return false
+}
+
+func (s *Server) removeTree(fileId string) {
+	for _, item := range s.items {
+		if item.ParentFileId == fileId {
+			s.removeTree(item.FileId)
+		}
+	}
+	delete(s.items, fileId)
+	delete(s.contents, fileId)
+}
+
+// 将目录树移动到另一个网盘
+func (s *Server) moveTree(item *aliyundrive.Item, driveId string) {
+	for _, child := range s.children(item.DriveId, item.FileId) {
+		s.moveTree(child, driveId)
+	}
+	item.DriveId = driveId
+}
+
+func sortItems(items []*aliyundrive.Item, orderBy string, orderDirection string) {
+	less := func(a, b *aliyundrive.Item) bool {
+		switch orderBy {
+		case aliyundrive.OrderByUpdatedAt:
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+		case aliyundrive.OrderByCreatedAt:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.FileId < b.FileId
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if orderDirection == aliyundrive.OrderDirectionDesc {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+}
+
+type pageParams struct {
+	OrderBy        string `json:"order_by"`
+	OrderDirection string `json:"order_direction"`
+	Limit          int    `json:"limit"`
+	Marker         string `json:"marker"`
+}
+
+// 对已排好序的条目分页，marker为下一页的起始下标
+func (p pageParams) page(items []*aliyundrive.Item) aliyundrive.Object {
+	sortItems(items, p.OrderBy, p.OrderDirection)
+	limit := p.Limit
+	if limit <= 0 || limit > aliyundrive.LimitMax {
+		limit = 100
+	}
+	start, _ := strconv.Atoi(p.Marker)
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + limit
+	nextMarker := strconv.Itoa(end)
+	if end >= len(items) {
+		end = len(items)
+		nextMarker = ""
+	}
+	result := make([]*aliyundrive.Item, 0, end-start)
+	for _, item := range items[start:end] {
+		result = append(result, copyItem(item))
+	}
+	return aliyundrive.Object{
+		"items":       result,
+		"next_marker": nextMarker,
+	}
+}
+
+func writeFileNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "NotFound.File", "The resource file cannot be found. file not exist")
+}
+
+func writeFileExist(w http.ResponseWriter) {
+	writeError(w, http.StatusConflict, "AlreadyExist.File", "The resource file has already exists. file already exist")
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId      string `json:"drive_id"`
+		ParentFileId string `json:"parent_file_id"`
+		pageParams
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	parent, ok := s.item(params.DriveId, params.ParentFileId)
+	if !ok || parent.Trashed || parent.Type != "folder" {
+		writeFileNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, params.page(s.children(params.DriveId, params.ParentFileId)))
+}
+
+// 搜索条件，支持name match、name =、parent_file_id =，多个条件以and连接，
+// 值中的双引号和反斜杠以反斜杠转义；
+// 每次匹配开头的一个条件，值中可能包含" and "，不能直接按" and "拆分
+var searchQueryRegexp = regexp.MustCompile(`^(name|parent_file_id) (match|=) "((?:[^"\\]|\\.)*)"( and |$)`)
+
+var searchValueUnescaper = regexp.MustCompile(`\\(.)`)
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		Query   string `json:"query"`
+		pageParams
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+	var conditions [][]string
+	for query := params.Query; ; {
+		match := searchQueryRegexp.FindStringSubmatch(query)
+		if match == nil || (match[1] == "parent_file_id" && match[2] == "match") {
+			writeError(w, http.StatusBadRequest, "InvalidParameter.Query", "The input parameter query is not valid.")
+			return
+		}
+		match[3] = searchValueUnescaper.ReplaceAllString(match[3], "$1")
+		conditions = append(conditions, match[1:4])
+		query = query[len(match[0]):]
+		if match[4] == "" {
+			break
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var items []*aliyundrive.Item
+	for _, item := range s.items {
+		if item.DriveId != params.DriveId || item.Trashed {
+			continue
+		}
+		if matchSearchConditions(item, conditions) {
+			items = append(items, item)
+		}
+	}
+	writeJSON(w, http.StatusOK, params.page(items))
+}
+
+func matchSearchConditions(item *aliyundrive.Item, conditions [][]string) bool {
+	for _, condition := range conditions {
+		field, operator, value := condition[0], condition[1], condition[2]
+		switch {
+		case field == "parent_file_id":
+			if item.ParentFileId != value {
+				return false
+			}
+		case operator == "match":
+			if !strings.Contains(item.Name, value) {
+				return false
+			}
+		case item.Name != value:
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.GetRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok {
+		writeFileNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleGetByPath(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.GetByPathRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+	if !strings.HasPrefix(params.FilePath, "/") {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.FilePath", "The input parameter file_path is not valid.")
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, aliyundrive.RootFileId)
+	if !ok {
+		writeFileNotFound(w)
+		return
+	}
+	for _, name := range strings.Split(path.Clean(params.FilePath), "/") {
+		if name == "" {
+			continue
+		}
+		if item = s.childByName(params.DriveId, item.FileId, name); item == nil {
+			writeFileNotFound(w)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleGetPath(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.GetPathRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok || item.Trashed {
+		writeFileNotFound(w)
+		return
+	}
+	// 从自身往上直到根目录，不包括根目录
+	items := []*aliyundrive.Item{}
+	for item.FileId != aliyundrive.RootFileId {
+		items = append(items, item)
+		if item, ok = s.item(params.DriveId, item.ParentFileId); !ok {
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, aliyundrive.GetPathResponse{
+		Items: items,
+	})
+}
+
+func (s *Server) handleGetDownloadUrl(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.GetDownloadUrlRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok || item.Trashed {
+		writeFileNotFound(w)
+		return
+	}
+	if item.Type != "file" {
+		writeError(w, http.StatusBadRequest, "InvalidResource.FileTypeFolder", "Download a folder is not supported.")
+		return
+	}
+	url := s.signedUrl("/download/" + item.FileId)
+	writeJSON(w, http.StatusOK, aliyundrive.GetDownloadUrlResponse{
+		FileId:          item.FileId,
+		Size:            item.Size,
+		ContentHash:     item.ContentHash,
+		ContentHashName: item.ContentHashName,
+		Expiration:      time.Now().Add(s.UrlTTL),
+		InternalUrl:     url,
+		Url:             url,
+	})
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	fileId := strings.TrimPrefix(r.URL.Path, "/download/")
+
+	s.lock.Lock()
+	item, itemOk := s.items[fileId]
+	data, dataOk := s.contents[fileId]
+	s.lock.Unlock()
+	if !itemOk || !dataOk {
+		writeOssError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	http.ServeContent(w, r, item.Name, item.UpdatedAt, bytes.NewReader(data))
+}
+
+func (s *Server) handleGetFolderSizeInfo(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.GetFolderSizeInfoRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	folder, ok := s.item(params.DriveId, params.FileId)
+	if !ok || folder.Type != "folder" {
+		writeFileNotFound(w)
+		return
+	}
+	result := new(aliyundrive.GetFolderSizeInfoResponse)
+	var walk func(parentFileId string)
+	walk = func(parentFileId string) {
+		for _, item := range s.children(params.DriveId, parentFileId) {
+			if item.Type == "folder" {
+				result.FolderCount++
+				walk(item.FileId)
+			} else {
+				result.FileCount++
+				result.Size += item.Size
+			}
+		}
+	}
+	walk(folder.FileId)
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleCreateWithFolders(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId       string `json:"drive_id"`
+		Name          string `json:"name"`
+		ParentFileId  string `json:"parent_file_id"`
+		Type          string `json:"type"`
+		CheckNameMode string `json:"check_name_mode"`
+		Size          uint64 `json:"size"`
+		PreHash       string `json:"pre_hash"`
+		ContentHash   string `json:"content_hash"`
+		ProofCode     string `json:"proof_code"`
+		PartInfoList  []*struct {
+			PartNumber int `json:"part_number"`
+		} `json:"part_info_list"`
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+	if params.Name == "" {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.Name", "The input parameter name is not valid.")
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	parent, ok := s.item(params.DriveId, params.ParentFileId)
+	if !ok || parent.Trashed || parent.Type != "folder" {
+		writeError(w, http.StatusNotFound, "NotFound.ParentFileId", "The resource parent_file_id cannot be found.")
+		return
+	}
+
+	name := params.Name
+	if exist := s.childByName(params.DriveId, params.ParentFileId, name); exist != nil {
+		switch params.CheckNameMode {
+		case "auto_rename":
+			name = s.availableName(params.DriveId, params.ParentFileId, name)
+		case "ignore":
+		case "overwrite":
+			if params.Type == "folder" || exist.Type == "folder" {
+				writeCreateResult(w, exist, true)
+				return
+			}
+		default:
+			writeCreateResult(w, exist, true)
+			return
+		}
+	}
+
+	item := s.newItem(params.DriveId, params.ParentFileId, name, params.Type)
+	if params.Type == "folder" {
+		s.items[item.FileId] = item
+		writeCreateResult(w, item, false)
+		return
+	}
+
+	// 秒传
+	if params.PreHash != "" && s.findByPreHash(params.PreHash) != nil {
+		writeError(w, http.StatusConflict, "PreHashMatched", "Pre hash matched.")
+		return
+	}
+	if params.ContentHash != "" {
+		if source := s.findByContentHash(params.ContentHash); source != nil {
+			if params.CheckNameMode == "overwrite" {
+				s.removeByName(params.DriveId, params.ParentFileId, name)
+			}
+			s.setContent(item, s.contents[source.FileId])
+			s.items[item.FileId] = item
+			result := createResult(item, false)
+			result["rapid_upload"] = true
+			writeJSON(w, http.StatusCreated, result)
+			return
+		}
+	}
+
+	// 普通分片上传
+	item.Size = params.Size
+	item.Status = "uploading"
+	item.UploadId = randomId()
+	up := &upload{fileId: item.FileId, item: item, overwrite: params.CheckNameMode == "overwrite", parts: make(map[int][]byte)}
+	s.uploads[item.UploadId] = up
+
+	partInfoList := make(aliyundrive.Array, 0, len(params.PartInfoList))
+	for _, part := range params.PartInfoList {
+		url := s.signedUrl("/upload/" + item.UploadId + "/" + strconv.Itoa(part.PartNumber))
+		partInfoList = append(partInfoList, aliyundrive.Object{
+			"part_number":         part.PartNumber,
+			"upload_url":          url,
+			"internal_upload_url": url,
+			"content_type":        "",
+		})
+	}
+	result := createResult(item, false)
+	result["upload_id"] = item.UploadId
+	result["part_info_list"] = partInfoList
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func (s *Server) findByPreHash(preHash string) *aliyundrive.Item {
+	for fileId, data := range s.contents {
+		if len(data) > 1024 {
+			data = data[:1024]
+		}
+		sum := sha1.Sum(data)
+		if strings.EqualFold(hex.EncodeToString(sum[:]), preHash) {
+			return s.items[fileId]
+		}
+	}
+	return nil
+}
+
+func (s *Server) findByContentHash(contentHash string) *aliyundrive.Item {
+	for _, item := range s.items {
+		if item.Type == "file" && strings.EqualFold(item.ContentHash, contentHash) {
+			return item
+		}
+	}
+	return nil
+}
+
+func (s *Server) removeByName(driveId string, parentFileId string, name string) {
+	if exist := s.childByName(driveId, parentFileId, name); exist != nil {
+		s.removeTree(exist.FileId)
+	}
+}
+
+func createResult(item *aliyundrive.Item, exist bool) aliyundrive.Object {
+	return aliyundrive.Object{
+		"file_id":        item.FileId,
+		"file_name":      item.Name,
+		"parent_file_id": item.ParentFileId,
+		"type":           item.Type,
+		"encrypt_mode":   "none",
+		"rapid_upload":   false,
+		"exist":          exist,
+	}
+}
+
+func writeCreateResult(w http.ResponseWriter, item *aliyundrive.Item, exist bool) {
+	writeJSON(w, http.StatusCreated, createResult(item, exist))
+}
+
+func (s *Server) handleUploadPart(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/upload/"), "/")
+	if len(parts) != 2 {
+		writeOssError(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return
+	}
+	partNumber, err := strconv.Atoi(parts[1])
+	if err != nil {
+		writeOssError(w, http.StatusBadRequest, "InvalidArgument", "Part number must be an integer.")
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOssError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	up, ok := s.uploads[parts[0]]
+	if !ok {
+		writeOssError(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return
+	}
+	up.parts[partNumber] = data
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.CompleteUploadFileRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	up, ok := s.uploads[params.UploadId]
+	if !ok || up.fileId != params.FileId || up.item.DriveId != params.DriveId {
+		writeError(w, http.StatusNotFound, "NotFound.UploadId", "The resource upload_id cannot be found.")
+		return
+	}
+
+	partNumbers := make([]int, 0, len(up.parts))
+	for partNumber := range up.parts {
+		partNumbers = append(partNumbers, partNumber)
+	}
+	sort.Ints(partNumbers)
+	data := make([]byte, 0, up.item.Size)
+	for _, partNumber := range partNumbers {
+		data = append(data, up.parts[partNumber]...)
+	}
+	if uint64(len(data)) != up.item.Size {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.Size", "The uploaded data size does not match.")
+		return
+	}
+
+	delete(s.uploads, params.UploadId)
+	if up.overwrite {
+		s.removeByName(up.item.DriveId, up.item.ParentFileId, up.item.Name)
+	}
+	item := up.item
+	item.Status = "available"
+	item.UploadId = ""
+	s.setContent(item, data)
+	s.items[item.FileId] = item
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId       string `json:"drive_id"`
+		FileId        string `json:"file_id"`
+		Name          string `json:"name"`
+		CheckNameMode string `json:"check_name_mode"`
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok {
+		writeFileNotFound(w)
+		return
+	}
+	name := params.Name
+	if exist := s.childByName(item.DriveId, item.ParentFileId, name); exist != nil && exist != item {
+		switch params.CheckNameMode {
+		case "auto_rename":
+			name = s.availableName(item.DriveId, item.ParentFileId, name)
+		case "ignore":
+		default:
+			writeFileExist(w)
+			return
+		}
+	}
+	item.Name = name
+	item.UpdatedAt = time.Now()
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId        string `json:"drive_id"`
+		ToDriveId      string `json:"to_drive_id"`
+		FileId         string `json:"file_id"`
+		ToParentFileId string `json:"to_parent_file_id"`
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if params.ToDriveId == "" {
+		params.ToDriveId = params.DriveId
+	}
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok || item.FileId == aliyundrive.RootFileId {
+		writeFileNotFound(w)
+		return
+	}
+	parent, ok := s.item(params.ToDriveId, params.ToParentFileId)
+	if !ok || parent.Trashed || parent.Type != "folder" {
+		writeError(w, http.StatusNotFound, "NotFound.ParentFileId", "The resource to_parent_file_id cannot be found.")
+		return
+	}
+	if s.isDescendant(params.ToDriveId, parent.FileId, item.FileId) {
+		writeError(w, http.StatusBadRequest, "ForbiddenMoveToSubFolder", "Can not move a folder into its sub folder.")
+		return
+	}
+	if exist := s.childByName(params.ToDriveId, parent.FileId, item.Name); exist != nil && exist != item {
+		writeFileExist(w)
+		return
+	}
+	item.ParentFileId = parent.FileId
+	item.UpdatedAt = time.Now()
+	s.moveTree(item, params.ToDriveId)
+	writeJSON(w, http.StatusOK, aliyundrive.Object{
+		"domain_id": "",
+		"drive_id":  item.DriveId,
+		"file_id":   item.FileId,
+	})
+}
+
+// 复制目录树到目的目录下，返回复制出的根，调用时需要持有锁
+func (s *Server) copyTree(item *aliyundrive.Item, driveId string, parentFileId string, name string) *aliyundrive.Item {
+	result := copyItem(item)
+	now := time.Now()
+	result.DriveId = driveId
+	result.FileId = randomId()
+	result.ParentFileId = parentFileId
+	result.Name = name
+	result.CreatedAt = now
+	result.UpdatedAt = now
+	s.items[result.FileId] = result
+	if data, ok := s.contents[item.FileId]; ok {
+		s.contents[result.FileId] = append([]byte(nil), data...)
+	}
+	for _, child := range s.children(item.DriveId, item.FileId) {
+		s.copyTree(child, driveId, result.FileId, child.Name)
+	}
+	return result
+}
+
+func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId   string `json:"drive_id"`
+		ToDriveId string `json:"to_drive_id"`
+		aliyundrive.CopyRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if params.ToDriveId == "" {
+		params.ToDriveId = params.DriveId
+	}
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok || item.Trashed || item.FileId == aliyundrive.RootFileId {
+		writeFileNotFound(w)
+		return
+	}
+	parent, ok := s.item(params.ToDriveId, params.ToParentFileId)
+	if !ok || parent.Trashed || parent.Type != "folder" {
+		writeError(w, http.StatusNotFound, "NotFound.ParentFileId", "The resource to_parent_file_id cannot be found.")
+		return
+	}
+	if s.isDescendant(params.ToDriveId, parent.FileId, item.FileId) {
+		writeError(w, http.StatusBadRequest, "ForbiddenCopyToSubFolder", "Can not copy a folder into its sub folder.")
+		return
+	}
+	name := params.NewName
+	if name == "" {
+		name = item.Name
+	}
+	if exist := s.childByName(params.ToDriveId, parent.FileId, name); exist != nil {
+		if !params.AutoRename {
+			writeFileExist(w)
+			return
+		}
+		name = s.availableName(params.ToDriveId, parent.FileId, name)
+	}
+
+	result := s.copyTree(item, params.ToDriveId, parent.FileId, name)
+	response := aliyundrive.CopyResponse{
+		DriveId: result.DriveId,
+		FileId:  result.FileId,
+	}
+	// 复制目录时返回异步任务
+	status := http.StatusOK
+	if result.Type == "folder" {
+		response.AsyncTaskId = s.newAsyncTask(s.treeSize(result))
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, response)
+}
+
+func (s *Server) handleTrash(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.TrashRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok || item.Trashed || item.FileId == aliyundrive.RootFileId {
+		writeFileNotFound(w)
+		return
+	}
+	item.Trashed = true
+	item.TrashedAt = time.Now()
+	result := aliyundrive.TrashResponse{
+		FileId: item.FileId,
+	}
+	if item.Type == "folder" {
+		result.AsyncTaskId = s.newAsyncTask(s.treeSize(item))
+	}
+	writeJSON(w, http.StatusAccepted, result)
+}
+
+func (s *Server) handleClearTrash(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var total int64
+	for fileId, item := range s.items {
+		if item.DriveId == params.DriveId && item.Trashed {
+			total += s.treeSize(item)
+			s.removeTree(fileId)
+		}
+	}
+	asyncTaskId := s.newAsyncTask(total)
+	writeJSON(w, http.StatusAccepted, aliyundrive.ClearTrashResponse{
+		AsyncTaskId: asyncTaskId,
+		TaskId:      asyncTaskId,
+	})
+}
+
+func (s *Server) handleListTrash(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		pageParams
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var items []*aliyundrive.Item
+	for _, item := range s.items {
+		if item.DriveId == params.DriveId && item.Trashed {
+			items = append(items, item)
+		}
+	}
+	writeJSON(w, http.StatusOK, params.page(items))
+}
+
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.RestoreRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok || !item.Trashed {
+		writeFileNotFound(w)
+		return
+	}
+	item.Trashed = false
+	item.TrashedAt = time.Time{}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		aliyundrive.DeleteRequest
+	}{}
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.item(params.DriveId, params.FileId)
+	if !ok || item.FileId == aliyundrive.RootFileId {
+		writeFileNotFound(w)
+		return
+	}
+	// 删除目录时返回异步任务
+	if item.Type == "folder" {
+		asyncTaskId := s.newAsyncTask(s.treeSize(item))
+		s.removeTree(item.FileId)
+		writeJSON(w, http.StatusAccepted, aliyundrive.DeleteResponse{
+			AsyncTaskId: asyncTaskId,
+		})
+		return
+	}
+	s.removeTree(item.FileId)
+	w.WriteHeader(http.StatusNoContent)
+}
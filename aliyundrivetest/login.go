@@ -0,0 +1,138 @@
+package aliyundrivetest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 扫码登录的二维码
+type qrCode struct {
+	t      int64
+	status string
+}
+
+// 模拟用户扫码，codeContent为生成二维码时返回的内容，二维码不存在或者状态不对时返回false
+func (s *Server) ScanQrCode(codeContent string) bool {
+	return s.setQrCodeStatus(codeContent, aliyundrive.QrCodeStatusNew, aliyundrive.QrCodeStatusScanned)
+}
+
+// 模拟用户在扫码之后确认登录
+func (s *Server) ConfirmQrCode(codeContent string) bool {
+	return s.setQrCodeStatus(codeContent, aliyundrive.QrCodeStatusScanned, aliyundrive.QrCodeStatusConfirmed)
+}
+
+// 模拟用户在扫码之后取消登录
+func (s *Server) CancelQrCode(codeContent string) bool {
+	return s.setQrCodeStatus(codeContent, aliyundrive.QrCodeStatusScanned, aliyundrive.QrCodeStatusCanceled)
+}
+
+// 使还没有确认的二维码过期
+func (s *Server) ExpireQrCode(codeContent string) bool {
+	return s.setQrCodeStatus(codeContent, "", aliyundrive.QrCodeStatusExpired)
+}
+
+// 将二维码的状态从from改为to，from为空表示任意未完成的状态
+func (s *Server) setQrCodeStatus(codeContent string, from string, to string) bool {
+	u, err := url.Parse(codeContent)
+	if err != nil {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	code, ok := s.qrCodes[u.Query().Get("lgToken")]
+	if !ok {
+		return false
+	}
+	switch {
+	case from != "" && code.status != from:
+		return false
+	case from == "" && code.status != aliyundrive.QrCodeStatusNew && code.status != aliyundrive.QrCodeStatusScanned:
+		return false
+	}
+	code.status = to
+	return true
+}
+
+func writePassportResult(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, aliyundrive.Object{
+		"content": aliyundrive.Object{
+			"data":    data,
+			"status":  0,
+			"success": true,
+		},
+		"hasError": false,
+	})
+}
+
+func writePassportError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusOK, aliyundrive.Object{
+		"content": aliyundrive.Object{
+			"data": aliyundrive.Object{
+				"titleMsg": message,
+			},
+			"status":  0,
+			"success": false,
+		},
+		"hasError": true,
+	})
+}
+
+func (s *Server) handleGenerateQrCode(w http.ResponseWriter, r *http.Request) {
+	ck := randomId()
+	t := time.Now().UnixMilli()
+	s.lock.Lock()
+	s.qrCodes[ck] = &qrCode{t: t, status: aliyundrive.QrCodeStatusNew}
+	s.lock.Unlock()
+
+	writePassportResult(w, aliyundrive.GenerateQrCodeResponse{
+		CodeContent: s.URL + "/qrcodeCheck.htm?lgToken=" + ck,
+		T:           t,
+		Ck:          ck,
+		ResultCode:  100,
+	})
+}
+
+func (s *Server) handleQueryQrCode(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writePassportError(w, err.Error())
+		return
+	}
+	t, _ := strconv.ParseInt(r.PostForm.Get("t"), 10, 64)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	code, ok := s.qrCodes[r.PostForm.Get("ck")]
+	if !ok || code.t != t {
+		writePassportError(w, "二维码不存在")
+		return
+	}
+	result := aliyundrive.QueryQrCodeResponse{
+		QrCodeStatus: code.status,
+		ResultCode:   100,
+	}
+	if code.status == aliyundrive.QrCodeStatusConfirmed {
+		accessToken := randomId()
+		s.accessTokens[accessToken] = time.Now().Add(time.Second * time.Duration(s.ExpiresIn))
+		bizExt, _ := json.Marshal(aliyundrive.Object{
+			"pds_login_result": aliyundrive.QrLoginResult{
+				UserId:         UserId,
+				UserName:       "aliyundrivetest",
+				NickName:       "aliyundrivetest",
+				Role:           "user",
+				DefaultDriveId: DriveId,
+				AccessToken:    accessToken,
+				RefreshToken:   s.RefreshToken,
+				ExpiresIn:      s.ExpiresIn,
+				TokenType:      "Bearer",
+			},
+		})
+		result.BizExt = base64.StdEncoding.EncodeToString(bizExt)
+	}
+	writePassportResult(w, result)
+}
@@ -0,0 +1,74 @@
+package aliyundrivetest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 模拟用户在授权页同意授权，返回一个只能使用一次的授权码
+func (s *Server) IssueOAuthCode() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	code := randomId()
+	s.oauthCodes[code] = true
+	return code
+}
+
+// 使用该模拟服务的client_id和client_secret的开放平台OAuth配置，不带授权码和refresh token
+func (s *Server) OAuthConfig() aliyundrive.OAuthConfig {
+	return aliyundrive.OAuthConfig{
+		ClientId:     s.ClientId,
+		ClientSecret: s.ClientSecret,
+		RedirectUri:  s.URL + "/oauth/callback",
+		Scope:        "user:base,file:all:read,file:all:write",
+	}
+}
+
+func (s *Server) handleOAuthAccessToken(w http.ResponseWriter, r *http.Request) {
+	params := new(aliyundrive.OAuthAccessTokenRequest)
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if params.ClientId != s.ClientId || params.ClientSecret != s.ClientSecret {
+		writeError(w, http.StatusUnauthorized, "InvalidClient", "The client_id or client_secret is not valid.")
+		return
+	}
+
+	// 客户端凭证模式不签发refresh token，其他模式签发之后轮换
+	var refreshToken string
+	switch params.GrantType {
+	case aliyundrive.GrantTypeAuthorizationCode:
+		if !s.oauthCodes[params.Code] {
+			writeError(w, http.StatusBadRequest, "InvalidCode", "The code is not valid.")
+			return
+		}
+		delete(s.oauthCodes, params.Code)
+		s.RefreshToken = randomId()
+		refreshToken = s.RefreshToken
+	case aliyundrive.GrantTypeRefreshToken:
+		if params.RefreshToken == "" || params.RefreshToken != s.RefreshToken {
+			writeError(w, http.StatusBadRequest, "InvalidParameter.RefreshToken", "The input parameter refresh_token is not valid.")
+			return
+		}
+		s.RefreshToken = randomId()
+		refreshToken = s.RefreshToken
+	case aliyundrive.GrantTypeClientCredentials:
+	default:
+		writeError(w, http.StatusBadRequest, "InvalidParameter.GrantType", "The input parameter grant_type is not valid.")
+		return
+	}
+
+	accessToken := randomId()
+	s.accessTokens[accessToken] = time.Now().Add(time.Second * time.Duration(s.ExpiresIn))
+	writeJSON(w, http.StatusOK, aliyundrive.OAuthAccessTokenResponse{
+		TokenType:    "Bearer",
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.ExpiresIn,
+	})
+}
@@ -0,0 +1,403 @@
+// 模拟阿里云盘服务的测试包
+//
+// 启动一个本地的httptest.Server，实现SDK调用到的接口，
+// 内部使用内存目录树以及真实的分片上传存储，
+// 便于在没有真实账号的情况下端到端地测试Drive、fs包等
+package aliyundrivetest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+const (
+	// 模拟服务的用户Id
+	UserId = "aliyundrivetest-user"
+	// 模拟服务的默认网盘Id，同时也是备份盘
+	DriveId = "1"
+	// 模拟服务的资源库网盘Id
+	ResourceDriveId = "2"
+)
+
+// 模拟阿里云盘服务
+type Server struct {
+	*httptest.Server
+
+	// 当前有效的refresh token，每次刷新之后都会轮换
+	RefreshToken string
+	// 签发accesstoken的有效期（秒）
+	ExpiresIn int64
+	// 上传/下载地址的有效期
+	UrlTTL time.Duration
+	// 异步任务被查询多少次之后才执行完成
+	AsyncTaskPolls int
+	// 开放平台应用的client_id和client_secret
+	ClientId     string
+	ClientSecret string
+
+	lock         *sync.Mutex
+	accessTokens map[string]time.Time
+	// 网盘Id -> 根目录
+	roots map[string]*aliyundrive.Item
+	// 文件Id -> 除根目录以外的文件/目录
+	items    map[string]*aliyundrive.Item
+	contents map[string][]byte
+	uploads  map[string]*upload
+	faults   map[string][]fault
+	// 设备Id -> 设备session
+	sessions map[string]*session
+	// ck -> 扫码登录的二维码
+	qrCodes map[string]*qrCode
+	// 开放平台尚未使用的授权码
+	oauthCodes map[string]bool
+	// 异步任务Id -> 异步任务
+	asyncTasks map[string]*asyncTask
+	// 下一个创建的异步任务的失败信息
+	asyncTaskFailure *asyncTask
+}
+
+// 通过create_session创建的设备session
+type session struct {
+	deviceId   string
+	deviceName string
+	modelName  string
+	loginTime  time.Time
+	publicKey  *ecdsa.PublicKey
+	// 当前有效的签名及其nonce，续期后更新
+	signature string
+	nonce     int
+	// 被踢下线之后使用该签名会返回UserDeviceOffline
+	online bool
+}
+
+// 注入的故障
+type fault struct {
+	status int
+	code   string
+}
+
+// 未完成的分片上传
+type upload struct {
+	fileId    string
+	item      *aliyundrive.Item
+	overwrite bool
+	parts     map[int][]byte
+}
+
+// 创建并启动一个模拟服务
+//
+// 使用完成后需要调用Close关闭
+func NewServer() *Server {
+	s := &Server{
+		RefreshToken:   randomId(),
+		ExpiresIn:      7200,
+		UrlTTL:         time.Minute * 15,
+		AsyncTaskPolls: 2,
+		ClientId:       randomId(),
+		ClientSecret:   randomId(),
+		lock:           new(sync.Mutex),
+		accessTokens:   make(map[string]time.Time),
+		roots:          make(map[string]*aliyundrive.Item),
+		items:          make(map[string]*aliyundrive.Item),
+		contents:       make(map[string][]byte),
+		uploads:        make(map[string]*upload),
+		faults:         make(map[string][]fault),
+		sessions:       make(map[string]*session),
+		qrCodes:        make(map[string]*qrCode),
+		oauthCodes:     make(map[string]bool),
+		asyncTasks:     make(map[string]*asyncTask),
+	}
+	now := time.Now()
+	for _, driveId := range []string{DriveId, ResourceDriveId} {
+		s.roots[driveId] = &aliyundrive.Item{
+			DriveId:   driveId,
+			FileId:    aliyundrive.RootFileId,
+			Name:      "root",
+			Type:      "folder",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// 指向该模拟服务的接口地址配置
+func (s *Server) Endpoint() aliyundrive.Endpoint {
+	return aliyundrive.Endpoint{
+		ApiBase:      s.URL,
+		AuthBase:     s.URL,
+		PassportBase: s.URL,
+		OpenBase:     s.URL,
+	}
+}
+
+// 创建一个连接到该模拟服务并完成初始化的Drive
+//
+// opts会追加在模拟服务的refresh token、http客户端和接口地址选项之后
+func (s *Server) NewDrive(opts ...aliyundrive.Option) (*aliyundrive.Drive, error) {
+	s.lock.Lock()
+	refreshToken := s.RefreshToken
+	s.lock.Unlock()
+
+	opts = append([]aliyundrive.Option{
+		aliyundrive.WithRefreshToken(refreshToken),
+		aliyundrive.WithHttpClient(s.Client()),
+		aliyundrive.WithEndpoint(s.Endpoint()),
+	}, opts...)
+	return aliyundrive.New(context.Background(), opts...)
+}
+
+// 使所有已经签发的accesstoken失效
+func (s *Server) RevokeAccessTokens() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.accessTokens = make(map[string]time.Time)
+}
+
+// 使所有设备session下线，之后使用原来签名的请求会返回UserDeviceOffline
+func (s *Server) RevokeDeviceSessions() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, session := range s.sessions {
+		session.online = false
+	}
+}
+
+// 注入故障，接下来n次请求path时直接返回status状态码和code错误
+//
+// path为接口路径（如/v2/file/get），或者上传/下载数据的路径前缀/upload/、/download/
+func (s *Server) InjectFault(path string, n int, status int, code string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := 0; i < n; i++ {
+		s.faults[path] = append(s.faults[path], fault{status: status, code: code})
+	}
+}
+
+func (s *Server) nextFault(path string) (fault, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	faults := s.faults[path]
+	if len(faults) == 0 {
+		return fault{}, false
+	}
+	s.faults[path] = faults[1:]
+	return faults[0], true
+}
+
+// 若path有注入的故障，则返回故障响应
+func (s *Server) serveFault(w http.ResponseWriter, path string) bool {
+	f, ok := s.nextFault(path)
+	if ok {
+		writeError(w, f.status, f.code, "injected fault")
+	}
+	return ok
+}
+
+// 若上传/下载数据有注入的故障或者地址已过期，则返回OSS格式的故障响应
+func (s *Server) serveDataFault(w http.ResponseWriter, r *http.Request, path string) bool {
+	if f, ok := s.nextFault(path); ok {
+		writeOssError(w, f.status, f.code, "injected fault")
+		return true
+	}
+	expires, err := strconv.ParseInt(r.URL.Query().Get("x-oss-expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		writeOssError(w, http.StatusForbidden, "AccessDenied", "Request has expired.")
+		return true
+	}
+	return false
+}
+
+// 生成带过期时间的上传/下载地址
+func (s *Server) signedUrl(path string) string {
+	expires := time.Now().Add(s.UrlTTL).Unix()
+	return s.URL + path + "?x-oss-expires=" + strconv.FormatInt(expires, 10)
+}
+
+type apiHandler func(w http.ResponseWriter, r *http.Request)
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/upload/"):
+		if !s.serveDataFault(w, r, "/upload/") {
+			s.handleUploadPart(w, r)
+		}
+		return
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/download/"):
+		if !s.serveDataFault(w, r, "/download/") {
+			s.handleDownload(w, r)
+		}
+		return
+	}
+	if s.serveFault(w, r.URL.Path) {
+		return
+	}
+
+	// 登录接口不需要accesstoken，生成二维码为GET请求
+	switch r.URL.Path {
+	case "/newlogin/qrcode/generate.do":
+		s.handleGenerateQrCode(w, r)
+		return
+	case "/newlogin/qrcode/query.do":
+		s.handleQueryQrCode(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+		return
+	}
+
+	var handler apiHandler
+	credit, signed := true, true
+	switch r.URL.Path {
+	case "/token/refresh":
+		handler, credit, signed = s.handleRefreshToken, false, false
+	case "/oauth/access_token":
+		handler, credit, signed = s.handleOAuthAccessToken, false, false
+	case "/v2/user/get", "/adrive/v1.0/user/getDriveInfo":
+		handler, signed = s.handleGetUserInfo, false
+	case "/users/v1/users/device/create_session":
+		handler, signed = s.handleCreateSession, false
+	case "/users/v1/users/device/renew_session":
+		handler, signed = s.handleRenewSession, false
+	case "/users/v2/users/device_list":
+		handler = s.handleListDevices
+	case "/users/v2/users/device_logout":
+		handler = s.handleLogoutDevice
+	case "/v2/drive/list_my_drives":
+		handler = s.handleListDrives
+	case "/v2/databox/get_personal_info", "/adrive/v1.0/user/getSpaceInfo":
+		handler = s.handleGetPersonalInfo
+	case "/adrive/v3/file/list", "/adrive/v1.0/openFile/list":
+		handler = s.handleList
+	case "/adrive/v3/file/search", "/adrive/v1.0/openFile/search":
+		handler = s.handleSearch
+	case "/v2/file/get", "/adrive/v1.0/openFile/get":
+		handler = s.handleGet
+	case "/v2/file/get_download_url", "/adrive/v1.0/openFile/getDownloadUrl":
+		handler = s.handleGetDownloadUrl
+	case "/adrive/v1/file/get_by_path", "/adrive/v1.0/openFile/get_by_path":
+		handler = s.handleGetByPath
+	case "/adrive/v1/file/get_path":
+		handler = s.handleGetPath
+	case "/adrive/v1/file/get_folder_size_info":
+		handler = s.handleGetFolderSizeInfo
+	case "/adrive/v2/file/createWithFolders", "/adrive/v1.0/openFile/create":
+		handler = s.handleCreateWithFolders
+	case "/v2/file/complete", "/adrive/v1.0/openFile/complete":
+		handler = s.handleComplete
+	case "/v3/file/update", "/adrive/v1.0/openFile/update":
+		handler = s.handleUpdate
+	case "/v3/file/move", "/adrive/v1.0/openFile/move":
+		handler = s.handleMove
+	case "/v2/file/copy", "/adrive/v1.0/openFile/copy":
+		handler = s.handleCopy
+	case "/v2/recyclebin/trash", "/adrive/v1.0/openFile/recyclebin/trash":
+		handler = s.handleTrash
+	case "/v2/recyclebin/clear":
+		handler = s.handleClearTrash
+	case "/adrive/v2/recyclebin/list":
+		handler = s.handleListTrash
+	case "/v2/recyclebin/restore":
+		handler = s.handleRestore
+	case "/v3/file/delete", "/adrive/v1.0/openFile/delete":
+		handler = s.handleDelete
+	case "/v2/async_task/get", "/adrive/v1.0/openFile/async_task/get":
+		handler = s.handleGetAsyncTask
+	case "/v3/batch":
+		handler = s.handleBatch
+	default:
+		writeError(w, http.StatusNotFound, "NotFound.Api", "api not found: "+r.URL.Path)
+		return
+	}
+
+	if credit && !s.checkAccessToken(r) {
+		writeError(w, http.StatusUnauthorized, "AccessTokenInvalid", "AccessToken is invalid. ErrValidateTokenFailed")
+		return
+	}
+	if signed && !s.checkSignature(w, r) {
+		return
+	}
+	handler(w, r)
+}
+
+// 检查请求的签名是否为设备session当前的签名，没有带签名的请求不检查
+func (s *Server) checkSignature(w http.ResponseWriter, r *http.Request) bool {
+	signature := r.Header.Get("X-Signature")
+	if signature == "" {
+		return true
+	}
+	s.lock.Lock()
+	session, ok := s.sessions[r.Header.Get("X-Device-Id")]
+	s.lock.Unlock()
+	switch {
+	case !ok || session.signature != signature:
+		writeError(w, http.StatusBadRequest, "DeviceSessionSignatureInvalid", "The device session signature is invalid.")
+		return false
+	case !session.online:
+		writeError(w, http.StatusBadRequest, "UserDeviceOffline", "The user device is offline.")
+		return false
+	}
+	return true
+}
+
+func (s *Server) checkAccessToken(r *http.Request) bool {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	expireTime, ok := s.accessTokens[accessToken]
+	return ok && time.Now().Before(expireTime)
+}
+
+func randomId() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+func decodeParams(w http.ResponseWriter, r *http.Request, params any) bool {
+	err := json.NewDecoder(r.Body).Decode(params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidParameter", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("X-Ca-Request-Id", randomId())
+	writeJSON(w, status, aliyundrive.ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
+
+func writeOssError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("X-Oss-Request-Id", randomId())
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(aliyundrive.OssError{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,254 @@
+package aliyundrivetest
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dustinxie/ecc"
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	params := new(aliyundrive.RefreshTokenRequest)
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if params.RefreshToken == "" || params.RefreshToken != s.RefreshToken {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.RefreshToken", "The input parameter refresh_token is not valid.")
+		return
+	}
+
+	accessToken := randomId()
+	s.accessTokens[accessToken] = time.Now().Add(time.Second * time.Duration(s.ExpiresIn))
+	s.RefreshToken = randomId()
+	writeJSON(w, http.StatusOK, aliyundrive.RefreshTokenResponse{
+		RefreshToken: s.RefreshToken,
+		AccessToken:  accessToken,
+		ExpiresIn:    s.ExpiresIn,
+	})
+}
+
+func (s *Server) handleGetUserInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, aliyundrive.GetUserInfoResponse{
+		UserID:          UserId,
+		UserName:        "aliyundrivetest",
+		NickName:        "aliyundrivetest",
+		DefaultDriveID:  DriveId,
+		ResourceDriveId: ResourceDriveId,
+		BackupDriveId:   DriveId,
+		Status:          "enabled",
+		Role:            "user",
+	})
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	params := new(aliyundrive.CreateSessionRequest)
+	if !decodeParams(w, r, params) {
+		return
+	}
+	deviceId, signature := r.Header.Get("X-Device-Id"), r.Header.Get("X-Signature")
+	if params.PubKey == "" || signature == "" {
+		writeError(w, http.StatusBadRequest, "InvalidParameter", "pubKey and signature are required")
+		return
+	}
+	publicKey, err := parsePublicKey(params.PubKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.PubKey", err.Error())
+		return
+	}
+	if !verifySignature(publicKey, deviceId, 0, signature) {
+		writeError(w, http.StatusBadRequest, "DeviceSessionSignatureInvalid", "The device session signature is invalid.")
+		return
+	}
+
+	s.lock.Lock()
+	s.sessions[deviceId] = &session{
+		deviceId:   deviceId,
+		deviceName: params.DeviceName,
+		modelName:  params.ModelName,
+		loginTime:  time.Now(),
+		publicKey:  publicKey,
+		signature:  signature,
+		online:     true,
+	}
+	s.lock.Unlock()
+	writeJSON(w, http.StatusOK, aliyundrive.CreateSessionResponse{
+		Result:  true,
+		Success: true,
+	})
+}
+
+// 续期session，可以使用当前的签名，也可以使用nonce加1之后的新签名
+func (s *Server) handleRenewSession(w http.ResponseWriter, r *http.Request) {
+	signature := r.Header.Get("X-Signature")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions[r.Header.Get("X-Device-Id")]
+	switch {
+	case !ok:
+		writeError(w, http.StatusBadRequest, "DeviceSessionSignatureInvalid", "The device session signature is invalid.")
+		return
+	case !session.online:
+		writeError(w, http.StatusBadRequest, "UserDeviceOffline", "The user device is offline.")
+		return
+	case signature == session.signature:
+	case verifySignature(session.publicKey, session.deviceId, session.nonce+1, signature):
+		session.signature = signature
+		session.nonce++
+	default:
+		writeError(w, http.StatusBadRequest, "DeviceSessionSignatureInvalid", "The device session signature is invalid.")
+		return
+	}
+	writeJSON(w, http.StatusOK, aliyundrive.Object{
+		"result":  true,
+		"success": true,
+	})
+}
+
+func (s *Server) handleListDevices(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	devices := make([]aliyundrive.DeviceInfo, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		if !session.online {
+			continue
+		}
+		devices = append(devices, aliyundrive.DeviceInfo{
+			DeviceId:   session.deviceId,
+			DeviceName: session.deviceName,
+			ModelName:  session.modelName,
+			LoginTime:  session.loginTime.Format(time.RFC3339),
+		})
+	}
+	s.lock.Unlock()
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].DeviceId < devices[j].DeviceId
+	})
+	writeJSON(w, http.StatusOK, aliyundrive.ListDevicesResponse{
+		Result:  devices,
+		Success: true,
+	})
+}
+
+func (s *Server) handleLogoutDevice(w http.ResponseWriter, r *http.Request) {
+	params := new(aliyundrive.LogoutDeviceRequest)
+	if !decodeParams(w, r, params) {
+		return
+	}
+
+	s.lock.Lock()
+	session, ok := s.sessions[params.DeviceId]
+	if ok && session.online {
+		session.online = false
+	} else {
+		ok = false
+	}
+	s.lock.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound.Device", "The device cannot be found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, aliyundrive.LogoutDeviceResponse{
+		Result:  true,
+		Success: true,
+	})
+}
+
+func (s *Server) handleListDrives(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	usedSize := make(map[string]uint64)
+	for _, item := range s.items {
+		usedSize[item.DriveId] += item.Size
+	}
+	s.lock.Unlock()
+
+	writeJSON(w, http.StatusOK, aliyundrive.ListDrivesResponse{
+		Items: []*aliyundrive.DriveInfo{
+			{
+				DriveId:   DriveId,
+				DriveName: "backup",
+				DriveType: "normal",
+				Category:  "backup",
+				Owner:     UserId,
+				Status:    "enabled",
+				TotalSize: uint64(aliyundrive.TB),
+				UsedSize:  usedSize[DriveId],
+			},
+			{
+				DriveId:   ResourceDriveId,
+				DriveName: "resource",
+				DriveType: "normal",
+				Category:  "resource",
+				Owner:     UserId,
+				Status:    "enabled",
+				TotalSize: uint64(aliyundrive.TB),
+				UsedSize:  usedSize[ResourceDriveId],
+			},
+		},
+	})
+}
+
+func (s *Server) handleGetPersonalInfo(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	var usedSize uint64
+	for _, item := range s.items {
+		usedSize += item.Size
+	}
+	s.lock.Unlock()
+
+	writeJSON(w, http.StatusOK, aliyundrive.Object{
+		"personal_rights_info": aliyundrive.Object{
+			"name":       "aliyundrivetest",
+			"spu_id":     "non-vip",
+			"is_expires": false,
+			"privileges": aliyundrive.Array{},
+		},
+		"personal_space_info": aliyundrive.Object{
+			"total_size": uint64(aliyundrive.TB),
+			"used_size":  usedSize,
+		},
+	})
+}
+
+// 签名使用的网页版appId，与SDK保持一致
+const signatureAppId = "5dde4e1bdf9e4966b387ba58f4b3fdc3"
+
+// 解析create_session提交的未压缩格式的secp256k1公钥
+func parsePublicKey(pubKey string) (*ecdsa.PublicKey, error) {
+	data, err := hex.DecodeString(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 65 || data[0] != 0x04 {
+		return nil, errors.New("invalid public key")
+	}
+	publicKey := &ecdsa.PublicKey{
+		Curve: ecc.P256k1(),
+		X:     new(big.Int).SetBytes(data[1:33]),
+		Y:     new(big.Int).SetBytes(data[33:]),
+	}
+	if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, errors.New("invalid public key")
+	}
+	return publicKey, nil
+}
+
+// 校验对appId:deviceId:userId:nonce的签名
+func verifySignature(publicKey *ecdsa.PublicKey, deviceId string, nonce int, signature string) bool {
+	signatureData, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256([]byte(signatureAppId + ":" + deviceId + ":" + UserId + ":" + strconv.Itoa(nonce)))
+	return ecc.VerifyBytes(publicKey, sum[:], signatureData, ecc.RecID|ecc.LowerS)
+}
@@ -0,0 +1,103 @@
+package aliyundrive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 异步任务状态
+const (
+	// 执行中
+	AsyncTaskStateRunning = "Running"
+	// 执行成功
+	AsyncTaskStateSucceed = "Succeed"
+	// 执行失败
+	AsyncTaskStateFailed = "Failed"
+)
+
+// 等待异步任务时默认的查询间隔
+const asyncTaskPollInterval = time.Second
+
+type GetAsyncTaskRequest struct {
+	// 异步任务Id，必须
+	AsyncTaskId string `json:"async_task_id"`
+}
+
+type GetAsyncTaskResponse struct {
+	// 异步任务Id
+	AsyncTaskId string `json:"async_task_id"`
+	// 任务状态，AsyncTaskStateXXX
+	State string `json:"state"`
+	// 总的处理量
+	TotalProcess int64 `json:"total_process"`
+	// 已经完成的处理量
+	ConsumedProcess int64 `json:"consumed_process"`
+	// 失败的错误码，任务失败时才有
+	ErrorCode string `json:"err_code"`
+	// 失败的原因，任务失败时才有
+	ErrorMessage string `json:"err_message"`
+}
+
+// 任务是否已经结束
+func (r *GetAsyncTaskResponse) Done() bool {
+	return r.State != "" && r.State != AsyncTaskStateRunning
+}
+
+// 获取异步任务状态接口
+func (c *Drive) DoGetAsyncTaskRequest(ctx context.Context, request GetAsyncTaskRequest) (*GetAsyncTaskResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiAsyncTaskGet, request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(GetAsyncTaskResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// 等待异步任务结束，返回任务的最终状态
+//
+// 每隔pollInterval查询一次任务状态，为0则使用1秒；
+// 任务失败时同时返回最终状态和满足errors.Is(err, ErrAsyncTaskFailed)的错误
+func (c *Drive) WaitAsyncTask(ctx context.Context, asyncTaskId string, pollInterval time.Duration) (*GetAsyncTaskResponse, error) {
+	if pollInterval <= 0 {
+		pollInterval = asyncTaskPollInterval
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		resp, err := c.DoGetAsyncTaskRequest(ctx, GetAsyncTaskRequest{
+			AsyncTaskId: asyncTaskId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Done() {
+			if resp.State == AsyncTaskStateFailed {
+				return resp, fmt.Errorf("%w: %s %s: %s", ErrAsyncTaskFailed, asyncTaskId, resp.ErrorCode, resp.ErrorMessage)
+			}
+			return resp, nil
+		}
+		c.logger().Debug("aliyundrive: waiting async task", "async_task_id", asyncTaskId, "consumed", resp.ConsumedProcess, "total", resp.TotalProcess)
+		timer.Reset(pollInterval)
+	}
+}
+
+// 请求设置了等待并且返回了异步任务时，等待任务结束
+func (c *Drive) waitAsyncTask(ctx context.Context, wait bool, asyncTaskId string) (*GetAsyncTaskResponse, error) {
+	if !wait || asyncTaskId == "" {
+		return nil, nil
+	}
+	return c.WaitAsyncTask(ctx, asyncTaskId, asyncTaskPollInterval)
+}
@@ -0,0 +1,44 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+func TestTrashWaitFailed(t *testing.T) {
+	srv, c := newTestDrive(t)
+	dir := srv.AddFolder(aliyundrive.RootFileId, "docs")
+	srv.AddFile(dir.FileId, "a.txt", nil)
+
+	// 任务失败时同时返回带有最终状态的结果和错误
+	srv.FailNextAsyncTask("InternalError", "trash failed")
+	result, err := c.DoTrashRequest(context.Background(), aliyundrive.TrashRequest{FileId: dir.FileId, Wait: true})
+	if !errors.Is(err, aliyundrive.ErrAsyncTaskFailed) {
+		t.Fatalf("trash error = %v, want ErrAsyncTaskFailed", err)
+	}
+	if result == nil || result.AsyncTask == nil {
+		t.Fatalf("trash result = %+v, want the final async task status", result)
+	}
+	task := result.AsyncTask
+	if task.AsyncTaskId != result.AsyncTaskId || task.State != aliyundrive.AsyncTaskStateFailed || task.ErrorCode != "InternalError" || task.ErrorMessage != "trash failed" {
+		t.Fatalf("async task = %+v, want failed with InternalError", task)
+	}
+}
+
+func TestCopyWaitFailed(t *testing.T) {
+	srv, c := newTestDrive(t)
+	dir := srv.AddFolder(aliyundrive.RootFileId, "docs")
+	dst := srv.AddFolder(aliyundrive.RootFileId, "dst")
+
+	srv.FailNextAsyncTask("InternalError", "copy failed")
+	result, err := c.DoCopyRequest(context.Background(), aliyundrive.CopyRequest{FileId: dir.FileId, ToParentFileId: dst.FileId, Wait: true})
+	if !errors.Is(err, aliyundrive.ErrAsyncTaskFailed) {
+		t.Fatalf("copy error = %v, want ErrAsyncTaskFailed", err)
+	}
+	if result == nil || result.FileId == "" || result.AsyncTask == nil || result.AsyncTask.State != aliyundrive.AsyncTaskStateFailed {
+		t.Fatalf("copy result = %+v, want the copied file and the failed async task", result)
+	}
+}
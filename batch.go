@@ -0,0 +1,318 @@
+package aliyundrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// 批量接口单次最多的子请求数
+const BatchLimitMax = 100
+
+// 批量操作时同时进行的批量请求数
+const batchConcurrency = 4
+
+// 批量接口的子请求
+//
+// 一般通过NewBatchMoveRequest等方法创建
+type BatchSubRequest struct {
+	// 子请求Id，结果中原样返回，用于对应子请求的结果，一般为文件Id
+	//
+	// BatchMove等批量操作提交时会使用子请求的序号作为Id，同一文件出现多次也不会混淆
+	Id     string `json:"id"`
+	Method string `json:"method"`
+	// 子请求的接口路径，如/file/move
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    any               `json:"body"`
+
+	// 批量操作时是否等待子请求的异步任务完成
+	wait bool
+}
+
+func newBatchSubRequest(id string, url string, body any) *BatchSubRequest {
+	return &BatchSubRequest{
+		Id:      id,
+		Method:  "POST",
+		Url:     url,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    body,
+	}
+}
+
+// 创建移动文件/目录的子请求，参数与DoMoveRequest相同
+func (c *Drive) NewBatchMoveRequest(request MoveRequest) *BatchSubRequest {
+	return newBatchSubRequest(request.FileId, "/file/move", c.moveParams(request))
+}
+
+// 创建重命名文件的子请求，参数与DoRenameRequest相同
+func (c *Drive) NewBatchRenameRequest(request RenameRequest) *BatchSubRequest {
+	return newBatchSubRequest(request.FileId, "/file/update", c.renameParams(request))
+}
+
+// 创建文件/目录移到回收站的子请求，参数与DoTrashRequest相同
+//
+// Wait只在BatchTrash中生效，直接使用DoBatchRequest时需要自己等待异步任务
+func (c *Drive) NewBatchTrashRequest(request TrashRequest) *BatchSubRequest {
+	subRequest := newBatchSubRequest(request.FileId, "/recyclebin/trash", c.trashParams(request))
+	subRequest.wait = request.Wait
+	return subRequest
+}
+
+// 创建永久删除文件/目录的子请求，参数与DoDeleteRequest相同
+//
+// Wait只在BatchDelete中生效，直接使用DoBatchRequest时需要自己等待异步任务
+func (c *Drive) NewBatchDeleteRequest(request DeleteRequest) *BatchSubRequest {
+	subRequest := newBatchSubRequest(request.FileId, "/file/delete", c.deleteParams(request))
+	subRequest.wait = request.Wait
+	return subRequest
+}
+
+type BatchRequest struct {
+	// 子请求，最多BatchLimitMax个，必须
+	Requests []*BatchSubRequest `json:"requests"`
+	// 资源类型，可选，为空则使用file
+	Resource string `json:"resource"`
+}
+
+// 批量接口子请求的结果
+type BatchSubResponse struct {
+	// 对应子请求的Id
+	Id string `json:"id"`
+	// 子请求的http状态码
+	Status int `json:"status"`
+	// 子请求的返回内容，与单独调用对应接口的返回相同
+	Body json.RawMessage `json:"body"`
+}
+
+// 子请求失败时返回对应的*ErrorResponse，成功返回nil
+func (r *BatchSubResponse) Err() error {
+	if r.Status < 400 {
+		return nil
+	}
+	result := &ErrorResponse{StatusCode: r.Status}
+	json.Unmarshal(r.Body, result)
+	if result.Code == "" && result.Message == "" {
+		result.Message = http.StatusText(r.Status)
+	}
+	return result
+}
+
+type BatchResponse struct {
+	Responses []*BatchSubResponse `json:"responses"`
+}
+
+// 批量接口，一次提交多个子请求
+//
+// 整个请求成功时，每个子请求的成功与否需要通过对应结果的Err判断
+func (c *Drive) DoBatchRequest(ctx context.Context, request BatchRequest) (*BatchResponse, error) {
+	if len(request.Requests) > BatchLimitMax {
+		return nil, fmt.Errorf("aliyundrive: too many batch sub requests: %d > %d", len(request.Requests), BatchLimitMax)
+	}
+	if request.Resource == "" {
+		request.Resource = "file"
+	}
+
+	resp, err := c.requestWithCredit(ctx, apiBatch, request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(BatchResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// 批量操作中部分文件失败的错误
+//
+// 可以通过errors.Is判断是否有某类错误，如errors.Is(err, ErrNotFound)
+type BatchError struct {
+	// 文件Id -> 该文件操作失败的错误，同一文件的多个操作都失败时为errors.Join合并的错误
+	Errors map[string]error
+}
+
+// 只包含Id最小的文件的错误，保证同样的失败得到同样的信息
+func (e *BatchError) Error() string {
+	fileIds := e.fileIds()
+	if len(fileIds) == 0 {
+		return "aliyundrive: batch operations failed"
+	}
+	return fmt.Sprintf("aliyundrive: %d batch operations failed, %s: %v", len(fileIds), fileIds[0], e.Errors[fileIds[0]])
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, fileId := range e.fileIds() {
+		errs = append(errs, e.Errors[fileId])
+	}
+	return errs
+}
+
+// 按顺序排列的失败文件Id
+func (e *BatchError) fileIds() []string {
+	fileIds := make([]string, 0, len(e.Errors))
+	for fileId := range e.Errors {
+		fileIds = append(fileIds, fileId)
+	}
+	sort.Strings(fileIds)
+	return fileIds
+}
+
+// 批量移动文件/目录
+//
+// 按BatchLimitMax分批并行提交，部分失败时返回*BatchError
+func (c *Drive) BatchMove(ctx context.Context, requests []MoveRequest) error {
+	subRequests := make([]*BatchSubRequest, 0, len(requests))
+	for _, request := range requests {
+		subRequests = append(subRequests, c.NewBatchMoveRequest(request))
+	}
+	return c.runBatch(ctx, subRequests, func(ctx context.Context, i int) error {
+		_, err := c.DoMoveRequest(ctx, requests[i])
+		return err
+	})
+}
+
+// 批量重命名文件
+//
+// 按BatchLimitMax分批并行提交，部分失败时返回*BatchError
+func (c *Drive) BatchRename(ctx context.Context, requests []RenameRequest) error {
+	subRequests := make([]*BatchSubRequest, 0, len(requests))
+	for _, request := range requests {
+		subRequests = append(subRequests, c.NewBatchRenameRequest(request))
+	}
+	return c.runBatch(ctx, subRequests, func(ctx context.Context, i int) error {
+		_, err := c.DoRenameRequest(ctx, requests[i])
+		return err
+	})
+}
+
+// 批量将文件/目录移到回收站
+//
+// 按BatchLimitMax分批并行提交，部分失败时返回*BatchError；
+// 设置了Wait的文件会等待其异步任务完成，任务失败也算该文件失败
+func (c *Drive) BatchTrash(ctx context.Context, requests []TrashRequest) error {
+	subRequests := make([]*BatchSubRequest, 0, len(requests))
+	for _, request := range requests {
+		subRequests = append(subRequests, c.NewBatchTrashRequest(request))
+	}
+	return c.runBatch(ctx, subRequests, func(ctx context.Context, i int) error {
+		_, err := c.DoTrashRequest(ctx, requests[i])
+		return err
+	})
+}
+
+// 批量永久删除文件/目录
+//
+// 按BatchLimitMax分批并行提交，部分失败时返回*BatchError；
+// 设置了Wait的文件会等待其异步任务完成，任务失败也算该文件失败
+func (c *Drive) BatchDelete(ctx context.Context, requests []DeleteRequest) error {
+	subRequests := make([]*BatchSubRequest, 0, len(requests))
+	for _, request := range requests {
+		subRequests = append(subRequests, c.NewBatchDeleteRequest(request))
+	}
+	return c.runBatch(ctx, subRequests, func(ctx context.Context, i int) error {
+		_, err := c.DoDeleteRequest(ctx, requests[i])
+		return err
+	})
+}
+
+// 分批并行提交子请求，收集每个子请求的错误
+//
+// subRequests的Id为文件Id，提交时替换为子请求的序号，错误按文件Id收集，
+// 同一文件的多个子请求都失败时合并其错误；
+// 开放平台没有批量接口，使用开放平台接口时通过single逐个调用对应的接口
+func (c *Drive) runBatch(ctx context.Context, subRequests []*BatchSubRequest, single func(ctx context.Context, i int) error) error {
+	errs := make(map[string]error)
+	lock := new(sync.Mutex)
+	fail := func(fileId string, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if errs[fileId] != nil {
+			err = errors.Join(errs[fileId], err)
+		}
+		errs[fileId] = err
+	}
+
+	wg := new(sync.WaitGroup)
+	sem := make(chan struct{}, batchConcurrency)
+	for start := 0; start < len(subRequests); start += BatchLimitMax {
+		end := min(start+BatchLimitMax, len(subRequests))
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start int, chunk []*BatchSubRequest) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if c.Endpoint.Open {
+				for i, subRequest := range chunk {
+					if err := single(ctx, start+i); err != nil {
+						fail(subRequest.Id, err)
+					}
+				}
+				return
+			}
+
+			indexed := make([]*BatchSubRequest, 0, len(chunk))
+			for i, subRequest := range chunk {
+				subRequest := *subRequest
+				subRequest.Id = strconv.Itoa(start + i)
+				indexed = append(indexed, &subRequest)
+			}
+			resp, err := c.DoBatchRequest(ctx, BatchRequest{Requests: indexed})
+			if err != nil {
+				for _, subRequest := range chunk {
+					fail(subRequest.Id, err)
+				}
+				return
+			}
+			responses := make(map[string]*BatchSubResponse, len(resp.Responses))
+			for _, subResponse := range resp.Responses {
+				responses[subResponse.Id] = subResponse
+			}
+			for i, subRequest := range chunk {
+				subResponse, ok := responses[indexed[i].Id]
+				if !ok {
+					fail(subRequest.Id, errors.New("aliyundrive: missing batch sub response"))
+					continue
+				}
+				if err := subResponse.Err(); err != nil {
+					fail(subRequest.Id, err)
+					continue
+				}
+				if err := c.waitBatchSubTask(ctx, subRequest, subResponse); err != nil {
+					fail(subRequest.Id, err)
+				}
+			}
+		}(start, subRequests[start:end])
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+	return nil
+}
+
+// 子请求设置了等待时，等待子请求返回的异步任务完成
+func (c *Drive) waitBatchSubTask(ctx context.Context, subRequest *BatchSubRequest, subResponse *BatchSubResponse) error {
+	if !subRequest.wait || len(subResponse.Body) == 0 {
+		return nil
+	}
+	result := &struct {
+		AsyncTaskId string `json:"async_task_id"`
+	}{}
+	if err := json.Unmarshal(subResponse.Body, result); err != nil {
+		return err
+	}
+	_, err := c.waitAsyncTask(ctx, true, result.AsyncTaskId)
+	return err
+}
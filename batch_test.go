@@ -0,0 +1,84 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+func TestBatchMoveChunks(t *testing.T) {
+	counts := map[string]*atomic.Int32{"batch": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithInterceptors(countCalls(counts)))
+	dst := srv.AddFolder(aliyundrive.RootFileId, "dst")
+
+	var requests []aliyundrive.MoveRequest
+	for i := 0; i < 249; i++ {
+		file := srv.AddFile(aliyundrive.RootFileId, fmt.Sprintf("%03d.txt", i), nil)
+		requests = append(requests, aliyundrive.MoveRequest{FileId: file.FileId, ToParentFileId: dst.FileId})
+	}
+	requests = append(requests, aliyundrive.MoveRequest{FileId: "not-exist", ToParentFileId: dst.FileId})
+
+	err := c.BatchMove(context.Background(), requests)
+	if got := counts["batch"].Load(); got != 3 {
+		t.Fatalf("250 moves sent %d batch requests, want 3", got)
+	}
+	var batchErr *aliyundrive.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 {
+		t.Fatalf("batch move error = %v, want *BatchError with 1 failure", err)
+	}
+	if !errors.Is(batchErr.Errors["not-exist"], aliyundrive.ErrNotFound) || !errors.Is(err, aliyundrive.ErrNotFound) {
+		t.Fatalf("batch move errors = %v, want ErrNotFound for not-exist", batchErr.Errors)
+	}
+	for _, request := range requests[:249] {
+		if item, _ := srv.Item(request.FileId); item.ParentFileId != dst.FileId {
+			t.Fatalf("%s was not moved", item.Name)
+		}
+	}
+}
+
+func TestBatchSameFile(t *testing.T) {
+	srv, c := newTestDrive(t)
+	dst := srv.AddFolder(aliyundrive.RootFileId, "dst")
+	file := srv.AddFile(aliyundrive.RootFileId, "a.txt", nil)
+
+	// 同一文件的两个子请求分别失败和成功，结果不能互相覆盖
+	err := c.BatchMove(context.Background(), []aliyundrive.MoveRequest{
+		{FileId: file.FileId, ToParentFileId: "not-exist"},
+		{FileId: file.FileId, ToParentFileId: dst.FileId},
+	})
+	var batchErr *aliyundrive.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors[file.FileId], aliyundrive.ErrNotFound) {
+		t.Fatalf("batch move error = %v, want ErrNotFound for %s", err, file.FileId)
+	}
+	if item, _ := srv.Item(file.FileId); item.ParentFileId != dst.FileId {
+		t.Fatalf("a.txt parent = %s, want %s", item.ParentFileId, dst.FileId)
+	}
+}
+
+func TestBatchErrorMessage(t *testing.T) {
+	_, c := newTestDrive(t)
+
+	var requests []aliyundrive.TrashRequest
+	for _, fileId := range []string{"d-missing", "b-missing", "c-missing", "a-missing"} {
+		requests = append(requests, aliyundrive.TrashRequest{FileId: fileId})
+	}
+	var message string
+	for i := 0; i < 5; i++ {
+		err := c.BatchTrash(context.Background(), requests)
+		if err == nil {
+			t.Fatal("batch trash of missing files succeeded")
+		}
+		if i > 0 && err.Error() != message {
+			t.Fatalf("batch error message changed from %q to %q", message, err.Error())
+		}
+		message = err.Error()
+	}
+	if !strings.Contains(message, "4 batch operations failed, a-missing:") {
+		t.Fatalf("batch error message = %q, want the count and the smallest file Id", message)
+	}
+}
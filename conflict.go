@@ -0,0 +1,94 @@
+package aliyundrive
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// 同名冲突策略，即创建、重命名、上传时目录下已有同名文件/目录的处理方式
+type NameConflictPolicy string
+
+const (
+	// 不创建，创建时返回已存在的文件/目录并且Exist为true，重命名时返回AlreadyExist错误
+	NameConflictRefuse NameConflictPolicy = "refuse"
+	// 自动重命名为不冲突的名字
+	NameConflictAutoRename NameConflictPolicy = "auto_rename"
+	// 覆盖同名的文件，同名的是目录时与NameConflictRefuse相同
+	NameConflictOverwrite NameConflictPolicy = "overwrite"
+	// 忽略冲突，允许存在同名的文件/目录
+	NameConflictIgnore NameConflictPolicy = "ignore"
+)
+
+// 为空时使用默认的策略
+func (p NameConflictPolicy) or(defaultPolicy NameConflictPolicy) NameConflictPolicy {
+	if p == "" {
+		return defaultPolicy
+	}
+	return p
+}
+
+// 同名冲突的处理结果
+type NameConflictResult string
+
+const (
+	// 没有同名冲突，或者按NameConflictIgnore创建了同名的文件/目录
+	NameConflictNone NameConflictResult = ""
+	// 已存在同名的文件/目录，没有创建，返回的是已存在的
+	NameConflictExisted NameConflictResult = "existed"
+	// 自动重命名为了新的名字
+	NameConflictRenamed NameConflictResult = "renamed"
+	// 覆盖了同名的文件
+	NameConflictOverwritten NameConflictResult = "overwritten"
+)
+
+// 根据请求的名字和返回的结果判断同名冲突的处理结果
+//
+// name为请求的名字，fileName为实际的名字，exist为服务端返回的是否已存在，
+// overwritten为覆盖模式下请求前是否存在同名文件
+func nameConflictResult(name string, fileName string, exist bool, overwritten bool) NameConflictResult {
+	switch {
+	case exist:
+		return NameConflictExisted
+	case fileName != "" && fileName != name:
+		return NameConflictRenamed
+	case overwritten:
+		return NameConflictOverwritten
+	}
+	return NameConflictNone
+}
+
+// 覆盖模式下查询目录下是否已有同名的文件/目录，用于判断是否发生了覆盖，其他模式不查询
+func (c *Drive) nameExists(ctx context.Context, policy NameConflictPolicy, driveId string, parentFileId string, name string) (bool, error) {
+	if policy != NameConflictOverwrite {
+		return false, nil
+	}
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		Query   string `json:"query"`
+		Limit   int    `json:"limit"`
+	}{
+		DriveId: driveId,
+		Query:   `parent_file_id = ` + quoteQueryValue(parentFileId) + ` and name = ` + quoteQueryValue(name),
+		Limit:   1,
+	}
+
+	resp, err := c.requestWithCredit(ctx, apiFileSearch, params)
+	if err != nil {
+		return false, err
+	}
+
+	result := new(SearchResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return false, err
+	}
+	return len(result.Items) > 0, nil
+}
+
+var queryValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// 将搜索条件的值转义并加上双引号，文件名中可能有双引号和反斜杠
+func quoteQueryValue(value string) string {
+	return `"` + queryValueReplacer.Replace(value) + `"`
+}
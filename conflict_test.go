@@ -0,0 +1,45 @@
+package aliyundrive_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+func TestOverwriteQuotedName(t *testing.T) {
+	for _, name := range []string{`say "hi" \ now.txt`, "Tom and Jerry.mp4"} {
+		t.Run(name, func(t *testing.T) {
+			srv, c := newTestDrive(t)
+			srv.AddFile(aliyundrive.RootFileId, name, []byte("old"))
+
+			data := []byte("new")
+			created, err := c.DoCreateFileRequest(context.Background(), aliyundrive.CreateFileRequest{
+				Name:               name,
+				ParentFileId:       aliyundrive.RootFileId,
+				Size:               uint64(len(data)),
+				NameConflictPolicy: aliyundrive.NameConflictOverwrite,
+			})
+			if err != nil {
+				t.Fatalf("create with overwrite: %v", err)
+			}
+			if created.NameConflict != aliyundrive.NameConflictOverwritten {
+				t.Fatalf("name conflict = %q, want %q", created.NameConflict, aliyundrive.NameConflictOverwritten)
+			}
+			if _, err := c.DoUploadFileRequest(context.Background(), aliyundrive.UploadFileRequest{Url: created.PartInfoList[0].UploadUrl, File: bytes.NewReader(data)}); err != nil {
+				t.Fatalf("upload: %v", err)
+			}
+			completed, err := c.DoCompleteUploadFileRequest(context.Background(), aliyundrive.CompleteUploadFileRequest{FileId: created.FileId, UploadId: created.UploadId})
+			if err != nil {
+				t.Fatalf("complete upload: %v", err)
+			}
+			if completed.Name != name {
+				t.Fatalf("completed name = %q, want %q", completed.Name, name)
+			}
+			if got := downloadFile(t, c, completed.FileId); !bytes.Equal(got, data) {
+				t.Fatalf("content = %q, want %q", got, data)
+			}
+		})
+	}
+}
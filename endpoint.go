@@ -0,0 +1,147 @@
+package aliyundrive
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// 默认的业务API基础地址
+	DefaultApiBase = "https://api.aliyundrive.com"
+	// 默认的授权API基础地址
+	DefaultAuthBase = "https://api.aliyundrive.com"
+	// 默认的登录API基础地址
+	DefaultPassportBase = "https://passport.aliyundrive.com"
+	// 默认的开放平台API基础地址
+	DefaultOpenBase = "https://openapi.alipan.com"
+)
+
+// 使用开放平台接口时，调用了开放平台没有对应接口的方法
+var ErrOpenApiUnsupported = errors.New("aliyundrive: api not supported by open platform")
+
+// 云盘接口地址配置
+//
+// 默认指向阿里云盘官方服务，
+// 可修改为本地模拟服务、镜像或者代理的地址
+type Endpoint struct {
+	// 业务API基础地址，为空则使用DefaultApiBase
+	ApiBase string
+	// 授权API基础地址（如刷新token），为空则使用DefaultAuthBase
+	AuthBase string
+	// 登录API基础地址（如扫码登录），为空则使用DefaultPassportBase
+	PassportBase string
+	// 开放平台API基础地址（包括OAuth授权），为空则使用DefaultOpenBase
+	OpenBase string
+	// 是否使用开放平台接口
+	//
+	// 开启后接口调用会路由到开放平台对应的接口，不再需要设备签名，
+	// accesstoken需要通过开放平台的OAuth获取（如WithOAuth）；
+	// 开放平台没有对应接口的方法会返回ErrOpenApiUnsupported
+	Open bool
+	// 上传/下载数据地址的改写函数，可选
+	//
+	// 云盘接口返回的上传/下载地址都是OSS的地址，
+	// 可以通过该函数将其改写为代理或者本地服务的地址
+	RewriteUrl func(rawUrl string) string
+}
+
+// 返回补全默认值之后的地址配置
+func (e Endpoint) withDefaults() Endpoint {
+	if e.ApiBase == "" {
+		e.ApiBase = DefaultApiBase
+	}
+	if e.AuthBase == "" {
+		e.AuthBase = DefaultAuthBase
+	}
+	if e.PassportBase == "" {
+		e.PassportBase = DefaultPassportBase
+	}
+	if e.OpenBase == "" {
+		e.OpenBase = DefaultOpenBase
+	}
+	return e
+}
+
+// 改写上传/下载数据地址
+func (e Endpoint) rewrite(rawUrl string) string {
+	if e.RewriteUrl == nil {
+		return rawUrl
+	}
+	return e.RewriteUrl(rawUrl)
+}
+
+type apiBase int
+
+const (
+	apiBaseApi apiBase = iota
+	apiBaseAuth
+	apiBasePassport
+	apiBaseOpen
+)
+
+// 云盘接口描述
+type api struct {
+	// 接口名，用于拦截器等区分不同的接口
+	name string
+	base apiBase
+	path string
+	// 开放平台对应的接口路径，为空表示开放平台没有对应的接口
+	openPath string
+	retry    retryMode
+}
+
+var (
+	apiUserGet               = api{"user/get", apiBaseApi, "/v2/user/get", "/adrive/v1.0/user/getDriveInfo", retryIdempotent}
+	apiTokenRefresh          = api{"token/refresh", apiBaseAuth, "/token/refresh", "", retryThrottled}
+	apiCreateSession         = api{"session/create", apiBaseApi, "/users/v1/users/device/create_session", "", retryIdempotent}
+	apiRenewSession          = api{"session/renew", apiBaseApi, "/users/v1/users/device/renew_session", "", retryIdempotent}
+	apiDeviceList            = api{"device/list", apiBaseApi, "/users/v2/users/device_list", "", retryIdempotent}
+	apiDeviceLogout          = api{"device/logout", apiBaseApi, "/users/v2/users/device_logout", "", retryThrottled}
+	apiDriveList             = api{"drive/list", apiBaseApi, "/v2/drive/list_my_drives", "", retryIdempotent}
+	apiPersonalInfo          = api{"databox/get_personal_info", apiBaseApi, "/v2/databox/get_personal_info", "/adrive/v1.0/user/getSpaceInfo", retryIdempotent}
+	apiFileList              = api{"file/list", apiBaseApi, "/adrive/v3/file/list", "/adrive/v1.0/openFile/list", retryIdempotent}
+	apiFileSearch            = api{"file/search", apiBaseApi, "/adrive/v3/file/search", "/adrive/v1.0/openFile/search", retryIdempotent}
+	apiFileGet               = api{"file/get", apiBaseApi, "/v2/file/get", "/adrive/v1.0/openFile/get", retryIdempotent}
+	apiFileGetByPath         = api{"file/get_by_path", apiBaseApi, "/adrive/v1/file/get_by_path", "/adrive/v1.0/openFile/get_by_path", retryIdempotent}
+	apiFileGetPath           = api{"file/get_path", apiBaseApi, "/adrive/v1/file/get_path", "", retryIdempotent}
+	apiFileGetDownloadUrl    = api{"file/get_download_url", apiBaseApi, "/v2/file/get_download_url", "/adrive/v1.0/openFile/getDownloadUrl", retryIdempotent}
+	apiFileGetFolderSize     = api{"file/get_folder_size_info", apiBaseApi, "/adrive/v1/file/get_folder_size_info", "", retryIdempotent}
+	apiFileCreateWithFolders = api{"file/create", apiBaseApi, "/adrive/v2/file/createWithFolders", "/adrive/v1.0/openFile/create", retryThrottled}
+	apiFileComplete          = api{"file/complete", apiBaseApi, "/v2/file/complete", "/adrive/v1.0/openFile/complete", retryThrottled}
+	apiFileUpdate            = api{"file/update", apiBaseApi, "/v3/file/update", "/adrive/v1.0/openFile/update", retryThrottled}
+	apiFileMove              = api{"file/move", apiBaseApi, "/v3/file/move", "/adrive/v1.0/openFile/move", retryThrottled}
+	apiFileCopy              = api{"file/copy", apiBaseApi, "/v2/file/copy", "/adrive/v1.0/openFile/copy", retryThrottled}
+	apiFileDelete            = api{"file/delete", apiBaseApi, "/v3/file/delete", "/adrive/v1.0/openFile/delete", retryThrottled}
+	apiRecyclebinTrash       = api{"recyclebin/trash", apiBaseApi, "/v2/recyclebin/trash", "/adrive/v1.0/openFile/recyclebin/trash", retryThrottled}
+	apiRecyclebinClear       = api{"recyclebin/clear", apiBaseApi, "/v2/recyclebin/clear", "", retryThrottled}
+	apiRecyclebinList        = api{"recyclebin/list", apiBaseApi, "/adrive/v2/recyclebin/list", "", retryIdempotent}
+	apiRecyclebinRestore     = api{"recyclebin/restore", apiBaseApi, "/v2/recyclebin/restore", "", retryThrottled}
+	apiAsyncTaskGet          = api{"async_task/get", apiBaseApi, "/v2/async_task/get", "/adrive/v1.0/openFile/async_task/get", retryIdempotent}
+	apiBatch                 = api{"batch", apiBaseApi, "/v3/batch", "", retryThrottled}
+	apiQrCodeGenerate        = api{"qrcode/generate", apiBasePassport, "/newlogin/qrcode/generate.do", "", retryIdempotent}
+	apiQrCodeQuery           = api{"qrcode/query", apiBasePassport, "/newlogin/qrcode/query.do", "", retryIdempotent}
+	apiOAuthAccessToken      = api{"oauth/access_token", apiBaseOpen, "/oauth/access_token", "", retryThrottled}
+)
+
+// 获取接口的完整地址
+//
+// 使用开放平台接口时返回开放平台对应接口的地址，没有对应接口则返回ErrOpenApiUnsupported
+func (c *Drive) apiUrl(a api) (string, error) {
+	endpoint := c.Endpoint.withDefaults()
+	base, path := endpoint.ApiBase, a.path
+	switch a.base {
+	case apiBaseAuth:
+		base = endpoint.AuthBase
+	case apiBasePassport:
+		base = endpoint.PassportBase
+	case apiBaseOpen:
+		base = endpoint.OpenBase
+	}
+	if endpoint.Open && a.base != apiBaseOpen {
+		if a.openPath == "" {
+			return "", ErrOpenApiUnsupported
+		}
+		base, path = endpoint.OpenBase, a.openPath
+	}
+	return strings.TrimSuffix(base, "/") + path, nil
+}
@@ -0,0 +1,162 @@
+package aliyundrive
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 云盘接口常见错误的分类，配合errors.Is使用
+//
+// 例如：errors.Is(err, ErrNotFound) 可判断文件/目录不存在，
+// 具体的错误码仍可以通过errors.As取得*ErrorResponse后查看
+var (
+	// 资源不存在，对应NotFound.*错误码
+	ErrNotFound = errors.New("aliyundrive: not found")
+	// 资源已存在，对应AlreadyExist.*错误码
+	ErrAlreadyExist = errors.New("aliyundrive: already exist")
+	// 空间不足，对应QuotaExhausted.*错误码
+	ErrQuotaExhausted = errors.New("aliyundrive: quota exhausted")
+	// 请求过于频繁被限流，对应TooManyRequests错误码
+	ErrTooManyRequests = errors.New("aliyundrive: too many requests")
+	// accesstoken无效或者过期，对应AccessTokenInvalid、AccessTokenExpired错误码
+	ErrAccessTokenInvalid = errors.New("aliyundrive: access token invalid")
+	// 设备已下线，对应UserDeviceOffline错误码
+	ErrDeviceOffline = errors.New("aliyundrive: user device offline")
+	// 设备session签名无效，对应DeviceSessionSignatureInvalid错误码
+	ErrSignatureInvalid = errors.New("aliyundrive: device session signature invalid")
+	// 文件在回收站中，对应ForbiddenFileInTheRecycleBin错误码
+	ErrFileInRecycleBin = errors.New("aliyundrive: file in the recycle bin")
+	// 秒传预检查命中，对应PreHashMatched错误码
+	ErrPreHashMatched = errors.New("aliyundrive: pre hash matched")
+	// 参数错误，对应InvalidParameter.*错误码
+	ErrInvalidParameter = errors.New("aliyundrive: invalid parameter")
+	// 没有权限，对应Forbidden*错误码
+	ErrForbidden = errors.New("aliyundrive: forbidden")
+	// 上传/下载地址已过期，需要重新获取地址
+	ErrUrlExpired = errors.New("aliyundrive: url expired")
+	// 下载请求的Range不合法
+	ErrInvalidRange = errors.New("aliyundrive: invalid range")
+	// 异步任务执行失败
+	ErrAsyncTaskFailed = errors.New("aliyundrive: async task failed")
+)
+
+// 错误码与错误分类的对应规则
+var errorKinds = []struct {
+	code   string
+	prefix bool
+	kind   error
+}{
+	{"NotFound.", true, ErrNotFound},
+	{"AlreadyExist.", true, ErrAlreadyExist},
+	{"QuotaExhausted", true, ErrQuotaExhausted},
+	{"TooManyRequests", false, ErrTooManyRequests},
+	{"AccessTokenInvalid", false, ErrAccessTokenInvalid},
+	{"AccessTokenExpired", false, ErrAccessTokenInvalid},
+	{"UserDeviceOffline", false, ErrDeviceOffline},
+	{"DeviceSessionSignatureInvalid", false, ErrSignatureInvalid},
+	{"ForbiddenFileInTheRecycleBin", false, ErrFileInRecycleBin},
+	{"PreHashMatched", false, ErrPreHashMatched},
+	{"InvalidParameter", true, ErrInvalidParameter},
+	{"Forbidden", true, ErrForbidden},
+}
+
+// 阿里云盘API调用出错的错误信息
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// 请求Id，用于向云盘反馈问题
+	RequestId string `json:"requestId,omitempty"`
+	// http状态码
+	StatusCode int `json:"-"`
+}
+
+// 错误信息的描述
+func (r *ErrorResponse) Error() string {
+	return fmt.Sprintf(`{"code":"%v","message":"%v"}`, r.Code, r.Message)
+}
+
+// 判断错误是否属于target分类，用于支持errors.Is
+func (r *ErrorResponse) Is(target error) bool {
+	for _, rule := range errorKinds {
+		if rule.kind != target {
+			continue
+		}
+		if r.Code == rule.code || (rule.prefix && strings.HasPrefix(r.Code, rule.code)) {
+			return true
+		}
+	}
+
+	// 没有错误码的时候根据http状态码判断
+	if r.Code == "" {
+		switch r.StatusCode {
+		case 401:
+			return target == ErrAccessTokenInvalid
+		case 404:
+			return target == ErrNotFound
+		case 429:
+			return target == ErrTooManyRequests
+		}
+	}
+	return false
+}
+
+// 获取错误对应的云盘接口错误码或者OSS错误码，没有则返回空字符串
+func ErrorCode(err error) string {
+	var errResponse *ErrorResponse
+	if errors.As(err, &errResponse) {
+		return errResponse.Code
+	}
+	var ossError *OssError
+	if errors.As(err, &ossError) {
+		return ossError.Code
+	}
+	return ""
+}
+
+// 判断某个error是否是云盘接口返回的PreHashMatched错误
+//
+// 由于PreHashMatched用于秒传的情况，
+// 所以遇到该错误需要走秒传后续的逻辑
+func IsPreHashMatchedError(err error) bool {
+	return errors.Is(err, ErrPreHashMatched)
+}
+
+// 上传/下载数据时OSS返回的错误信息
+type OssError struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	RequestId string   `xml:"RequestId"`
+	HostId    string   `xml:"HostId"`
+	// http状态码
+	StatusCode int `xml:"-"`
+}
+
+// 错误信息的描述
+func (e *OssError) Error() string {
+	return fmt.Sprintf("aliyundrive: oss error: status=%v code=%v message=%v", e.StatusCode, e.Code, e.Message)
+}
+
+// 判断错误是否属于target分类，用于支持errors.Is
+func (e *OssError) Is(target error) bool {
+	switch target {
+	case ErrUrlExpired:
+		return e.expired()
+	case ErrForbidden:
+		return e.StatusCode == 403 && !e.expired()
+	case ErrNotFound:
+		return e.StatusCode == 404 || e.Code == "NoSuchKey"
+	case ErrInvalidRange:
+		return e.StatusCode == 416 || e.Code == "InvalidRange"
+	case ErrTooManyRequests:
+		return e.StatusCode == 429
+	}
+	return false
+}
+
+// 签名地址过期时OSS返回403 AccessDenied，Message为Request has expired.
+func (e *OssError) expired() bool {
+	return e.StatusCode == 403 && strings.Contains(strings.ToLower(e.Message), "expired")
+}
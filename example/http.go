@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net/http"
@@ -21,13 +22,11 @@ func main() {
 	flag.StringVar(&root, "root", "/", "root")
 	flag.Parse()
 
-	c := &aliyundrive.Drive{
-		RefreshToken: refreshToken,
-	}
-	if err := c.Init(); err != nil {
+	c, err := aliyundrive.New(context.Background(), aliyundrive.WithRefreshToken(refreshToken))
+	if err != nil {
 		log.Fatal(err)
 	}
-	defer c.Destory()
+	defer c.Close()
 
 	fsys := alifs.New(c, root)
 
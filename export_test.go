@@ -0,0 +1,14 @@
+package aliyundrive
+
+import "time"
+
+// 修改session的有效期和续期提前量，返回恢复原值的函数
+//
+// 只能在创建Drive之前调用，测试结束后需要恢复
+func SetSessionTTL(ttl time.Duration, renewAhead time.Duration) (restore func()) {
+	oldTTL, oldRenewAhead := sessionTTL, sessionRenewAhead
+	sessionTTL, sessionRenewAhead = ttl, renewAhead
+	return func() {
+		sessionTTL, sessionRenewAhead = oldTTL, oldRenewAhead
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"io"
 	"math/big"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -52,6 +55,7 @@ func GetProofStart(accessToken string, size uint64) uint64 {
 // 所以设计全部放在一个结构体内，在请求不同的接口或者不同文件目录类型、格式的时候，
 // 部分特别的字段可能会出现或者没有，需要调用者自己关注测试好
 type Item struct {
+	DriveId         string    `json:"drive_id"`
 	FileId          string    `json:"file_id"`
 	Name            string    `json:"name"`
 	ParentFileId    string    `json:"parent_file_id"`
@@ -120,7 +124,7 @@ type GetPersonalInfoResponse struct {
 
 // 获取用户个人及网盘信息接口
 func (c *Drive) DoGetPersonalInfoRequest(ctx context.Context, request GetPersonalInfoRequest) (*GetPersonalInfoResponse, error) {
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v2/databox/get_personal_info", Object{})
+	resp, err := c.requestWithCredit(ctx, apiPersonalInfo, Object{})
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +148,8 @@ type ListRequest struct {
 	Limit int `json:"limit,omitempty"`
 	// 分页标记，可选
 	NextMarker string `json:"marker,omitempty"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 type ListResponse struct {
 	// 列出的目录文件
@@ -159,11 +165,11 @@ func (c *Drive) DoListRequest(ctx context.Context, request ListRequest) (*ListRe
 		Fields  string `json:"fields"`
 		ListRequest
 	}{
-		DriveId:     c.driveId,
+		DriveId:     c.requestDriveId(request.DriveId),
 		Fields:      "*",
 		ListRequest: request,
 	}
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/adrive/v3/file/list", params)
+	resp, err := c.requestWithCredit(ctx, apiFileList, params)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +193,8 @@ type SearchRequest struct {
 	Limit int `json:"limit,omitempty"`
 	// 分页标记，可选
 	NextMarker string `json:"marker,omitempty"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type SearchResponse struct {
@@ -204,12 +212,12 @@ func (c *Drive) DoSearchRequest(ctx context.Context, request SearchRequest) (*Se
 		Query   string `json:"query"`
 		SearchRequest
 	}{
-		DriveId:       c.driveId,
+		DriveId:       c.requestDriveId(request.DriveId),
 		SearchRequest: request,
 	}
 	params.Query = `name match "` + params.Name + `"`
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/adrive/v3/file/search", params)
+	resp, err := c.requestWithCredit(ctx, apiFileSearch, params)
 	if err != nil {
 		return nil, err
 	}
@@ -225,6 +233,8 @@ func (c *Drive) DoSearchRequest(ctx context.Context, request SearchRequest) (*Se
 type GetRequest struct {
 	// 文件Id，必须
 	FileId string `json:"file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type GetResponse struct {
@@ -238,10 +248,10 @@ func (c *Drive) DoGetRequest(ctx context.Context, request GetRequest) (*GetRespo
 		DriveId string `json:"drive_id"`
 		GetRequest
 	}{
-		DriveId:    c.driveId,
+		DriveId:    c.requestDriveId(request.DriveId),
 		GetRequest: request,
 	}
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v2/file/get", params)
+	resp, err := c.requestWithCredit(ctx, apiFileGet, params)
 	if err != nil {
 		return nil, err
 	}
@@ -254,9 +264,80 @@ func (c *Drive) DoGetRequest(ctx context.Context, request GetRequest) (*GetRespo
 	return result, nil
 }
 
+type GetByPathRequest struct {
+	// 文件/目录的完整路径，以/开头，如/a/b/c.txt，必须
+	FilePath string `json:"file_path"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+}
+
+type GetByPathResponse struct {
+	Item
+}
+
+// 根据路径获取文件详细信息接口
+//
+// 一次调用即可定位任意深度的文件/目录，不存在时返回的错误满足errors.Is(err, ErrNotFound)
+func (c *Drive) DoGetByPathRequest(ctx context.Context, request GetByPathRequest) (*GetByPathResponse, error) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		GetByPathRequest
+	}{
+		DriveId:          c.requestDriveId(request.DriveId),
+		GetByPathRequest: request,
+	}
+	resp, err := c.requestWithCredit(ctx, apiFileGetByPath, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(GetByPathResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type GetPathRequest struct {
+	// 文件Id，必须
+	FileId string `json:"file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+}
+
+type GetPathResponse struct {
+	// 从文件/目录自身到根目录下第一级目录，按从下往上的顺序，不包括根目录
+	Items []*Item `json:"items"`
+}
+
+// 获取文件/目录的所有上级目录接口，可用于生成面包屑导航
+func (c *Drive) DoGetPathRequest(ctx context.Context, request GetPathRequest) (*GetPathResponse, error) {
+	params := &struct {
+		DriveId string `json:"drive_id"`
+		GetPathRequest
+	}{
+		DriveId:        c.requestDriveId(request.DriveId),
+		GetPathRequest: request,
+	}
+	resp, err := c.requestWithCredit(ctx, apiFileGetPath, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(GetPathResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 type GetDownloadUrlRequest struct {
 	// 文件Id，必须
 	FileId string `json:"file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type GetDownloadUrlResponse struct {
@@ -285,10 +366,10 @@ func (c *Drive) DoGetDownloadUrlRequest(ctx context.Context, request GetDownload
 		DriveId string `json:"drive_id"`
 		GetDownloadUrlRequest
 	}{
-		DriveId:               c.driveId,
+		DriveId:               c.requestDriveId(request.DriveId),
 		GetDownloadUrlRequest: request,
 	}
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v2/file/get_download_url", params)
+	resp, err := c.requestWithCredit(ctx, apiFileGetDownloadUrl, params)
 	if err != nil {
 		return nil, err
 	}
@@ -304,6 +385,8 @@ func (c *Drive) DoGetDownloadUrlRequest(ctx context.Context, request GetDownload
 type GetFolderSizeInfoRequest struct {
 	// 文件Id，必须
 	FileId string `json:"file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type GetFolderSizeInfoResponse struct {
@@ -322,10 +405,10 @@ func (c *Drive) DoGetFolderSizeInfoRequest(ctx context.Context, request GetFolde
 		DriveId string `json:"drive_id"`
 		GetFolderSizeInfoRequest
 	}{
-		DriveId:                  c.driveId,
+		DriveId:                  c.requestDriveId(request.DriveId),
 		GetFolderSizeInfoRequest: request,
 	}
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/adrive/v1/file/get_folder_size_info", params)
+	resp, err := c.requestWithCredit(ctx, apiFileGetFolderSize, params)
 	if err != nil {
 		return nil, err
 	}
@@ -343,6 +426,10 @@ type CreateFolderRequest struct {
 	Name string `json:"name"`
 	// 父级目录文件Id，必须
 	ParentFileId string `json:"parent_file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 同名冲突策略，可选，为空则使用NameConflictRefuse，即已存在时返回已存在的目录
+	NameConflictPolicy NameConflictPolicy `json:"-"`
 }
 
 type CreateFolderResponse struct {
@@ -355,6 +442,10 @@ type CreateFolderResponse struct {
 	// 类型
 	Type        string `json:"type"`
 	EncryptMode string `json:"encrypt_mode"`
+	// 是否已存在同名的文件/目录，为true时返回的是已存在的
+	Exist bool `json:"exist"`
+	// 同名冲突的处理结果
+	NameConflict NameConflictResult `json:"-"`
 }
 
 // 创建目录接口
@@ -365,13 +456,13 @@ func (c *Drive) DoCreateFolderRequest(ctx context.Context, request CreateFolderR
 		Type          string `json:"type"`
 		CreateFolderRequest
 	}{
-		DriveId:             c.driveId,
-		CheckNameMode:       "refuse",
+		DriveId:             c.requestDriveId(request.DriveId),
+		CheckNameMode:       string(request.NameConflictPolicy.or(NameConflictRefuse)),
 		Type:                "folder",
 		CreateFolderRequest: request,
 	}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/adrive/v2/file/createWithFolders", params)
+	resp, err := c.requestWithCredit(ctx, apiFileCreateWithFolders, params)
 	if err != nil {
 		return nil, err
 	}
@@ -381,6 +472,7 @@ func (c *Drive) DoCreateFolderRequest(ctx context.Context, request CreateFolderR
 	if err != nil {
 		return nil, err
 	}
+	result.NameConflict = nameConflictResult(request.Name, result.FileName, result.Exist, false)
 	return result, nil
 }
 
@@ -395,6 +487,10 @@ type CreateFileRequest struct {
 	PreHash string `json:"pre_hash"`
 	// 文件分片大小，必须
 	ChunkSize uint64 `json:"-"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 同名冲突策略，可选，为空则使用NameConflictAutoRename
+	NameConflictPolicy NameConflictPolicy `json:"-"`
 }
 
 type CreateFileResponse struct {
@@ -421,11 +517,16 @@ type CreateFileResponse struct {
 		// 分片上传地址
 		UploadUrl string `json:"upload_url"`
 	} `json:"part_info_list"`
+	// 是否已存在同名的文件/目录，为true时返回的是已存在的
+	Exist bool `json:"exist"`
+	// 同名冲突的处理结果
+	NameConflict NameConflictResult `json:"-"`
 }
 
 // 创建文件接口
 //
-// 若error返回的是PreHashMatched，表明可以尝试秒传上传
+// 若error返回的是PreHashMatched，表明可以尝试秒传上传；
+// 覆盖模式下会先查询一次是否有同名文件，覆盖在完成上传之后才生效
 func (c *Drive) DoCreateFileRequest(ctx context.Context, request CreateFileRequest) (*CreateFileResponse, error) {
 	params := &struct {
 		DriveId       string `json:"drive_id"`
@@ -436,8 +537,8 @@ func (c *Drive) DoCreateFileRequest(ctx context.Context, request CreateFileReque
 		PartInfoList  Array  `json:"part_info_list"`
 		CreateFileRequest
 	}{
-		DriveId:           c.driveId,
-		CheckNameMode:     "auto_rename",
+		DriveId:           c.requestDriveId(request.DriveId),
+		CheckNameMode:     string(request.NameConflictPolicy.or(NameConflictAutoRename)),
 		CreateScene:       "file_upload",
 		Type:              "file",
 		CreateFileRequest: request,
@@ -458,7 +559,11 @@ func (c *Drive) DoCreateFileRequest(ctx context.Context, request CreateFileReque
 		params.PartInfoList[i] = Object{"part_number": i}
 	}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/adrive/v2/file/createWithFolders", params)
+	overwritten, err := c.nameExists(ctx, request.NameConflictPolicy, params.DriveId, request.ParentFileId, request.Name)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.requestWithCredit(ctx, apiFileCreateWithFolders, params)
 	if err != nil {
 		return nil, err
 	}
@@ -468,6 +573,7 @@ func (c *Drive) DoCreateFileRequest(ctx context.Context, request CreateFileReque
 	if err != nil {
 		return nil, err
 	}
+	result.NameConflict = nameConflictResult(request.Name, result.FileName, result.Exist, overwritten)
 	return result, nil
 }
 
@@ -484,8 +590,10 @@ type DownloadFileResponse struct {
 }
 
 // 下载文件数据
+//
+// 下载地址过期时返回的错误满足errors.Is(err, ErrUrlExpired)，需要重新获取下载地址
 func (c *Drive) DoDownloadFileRequest(ctx context.Context, request DownloadFileRequest) (*DownloadFileResponse, error) {
-	httpRequest, err := http.NewRequestWithContext(ctx, "GET", request.Url, nil)
+	httpRequest, err := http.NewRequestWithContext(ctx, "GET", c.Endpoint.rewrite(request.Url), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -495,12 +603,14 @@ func (c *Drive) DoDownloadFileRequest(ctx context.Context, request DownloadFileR
 	httpRequest.Header.Set("Origin", "https://www.aliyundrive.com")
 	httpRequest.Header.Set("Referer", "https://www.aliyundrive.com/")
 
-	resp, err := c.HttpClient.Do(httpRequest)
+	resp, err := c.doDataRequest(ctx, OperationDownload, request, retryIdempotent, func(n int) (*http.Request, error) {
+		return httpRequest.Clone(ctx), nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &DownloadFileResponse{
-		Reader: resp.Body,
+		Reader: &countingReadCloser{ReadCloser: c.transferLimiter.readCloser(ctx, resp.Body), add: c.metrics().AddDownloadBytes},
 	}, nil
 }
 
@@ -515,15 +625,69 @@ type UploadFileResponse struct {
 }
 
 // 上传文件数据
+//
+// 上传地址过期时返回的错误满足errors.Is(err, ErrUrlExpired)，需要重新获取上传地址
 func (c *Drive) DoUploadFileRequest(ctx context.Context, request UploadFileRequest) (*UploadFileResponse, error) {
-	httpRequest, err := http.NewRequestWithContext(ctx, "PUT", request.Url, request.File)
-	if err != nil {
-		return nil, err
+	// 只有可以Seek的数据流才能在失败后从头重新上传
+	mode := retryNever
+	var start, size int64
+	seeker, seekable := request.File.(io.Seeker)
+	if seekable {
+		var err error
+		start, size, err = seekRange(seeker)
+		if err == nil {
+			mode = retryIdempotent
+		}
+	}
+
+	// 每次尝试都从头读取数据流，http客户端关闭的只是uploadBody，不会关闭调用者的数据流；
+	// http客户端返回响应之后可能仍在发送上一次的数据，先关闭上一次的uploadBody再Seek
+	var (
+		bodyLock sync.Mutex
+		body     *uploadBody
+	)
+	newBody := func() (io.ReadCloser, error) {
+		bodyLock.Lock()
+		defer bodyLock.Unlock()
+		if body != nil {
+			body.Close()
+		}
+		if mode == retryIdempotent {
+			if size == 0 {
+				return http.NoBody, nil
+			}
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		body = &uploadBody{r: request.File}
+		return &countingReadCloser{ReadCloser: c.transferLimiter.readCloser(ctx, body), add: c.metrics().AddUploadBytes}, nil
 	}
-	httpRequest.Header.Set("Origin", "https://www.aliyundrive.com")
-	httpRequest.Header.Set("Referer", "https://www.aliyundrive.com/")
 
-	resp, err := c.HttpClient.Do(httpRequest)
+	resp, err := c.doDataRequest(ctx, OperationUpload, request, mode, func(n int) (*http.Request, error) {
+		// 可以Seek的数据流的长度由Seek得到，不可以的由http.NewRequest按类型判断
+		var reader io.Reader
+		if mode != retryIdempotent {
+			reader = request.File
+		}
+		httpRequest, err := http.NewRequestWithContext(ctx, "PUT", c.Endpoint.rewrite(request.Url), reader)
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.Header.Set("Origin", "https://www.aliyundrive.com")
+		httpRequest.Header.Set("Referer", "https://www.aliyundrive.com/")
+		if mode == retryIdempotent {
+			httpRequest.ContentLength = size
+			httpRequest.GetBody = newBody
+		} else if httpRequest.Body == nil || httpRequest.Body == http.NoBody {
+			return httpRequest, nil
+		}
+		httpRequest.Body, err = newBody()
+		if err != nil {
+			return nil, err
+		}
+		return httpRequest, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -535,11 +699,78 @@ func (c *Drive) DoUploadFileRequest(ctx context.Context, request UploadFileReque
 	return &UploadFileResponse{}, nil
 }
 
+// 一次上传尝试的数据流，关闭之后不再读取调用者的数据流
+type uploadBody struct {
+	r      io.Reader
+	lock   sync.Mutex
+	closed bool
+}
+
+func (b *uploadBody) Read(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return 0, errors.New("aliyundrive: upload body closed")
+	}
+	return b.r.Read(p)
+}
+
+func (b *uploadBody) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	return nil
+}
+
+// 数据流当前的位置以及从该位置到结尾的长度，返回前会恢复到当前的位置
+func seekRange(seeker io.Seeker) (start int64, size int64, err error) {
+	start, err = seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	return start, end - start, nil
+}
+
+// 检查上传/下载数据请求的响应
+//
+// 状态码不是2xx时关闭响应，并将OSS返回的XML错误信息解析为*OssError
+func checkDataResponse(resp *http.Response) (retryDecision, error) {
+	decision := decideByResponse(resp)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return decision, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*KB))
+	resp.Body.Close()
+	if err != nil {
+		decision.temporary = true
+		return decision, err
+	}
+	ossError := new(OssError)
+	if xml.Unmarshal(data, ossError) != nil || ossError.Code == "" {
+		ossError.Message = resp.Status
+	}
+	ossError.StatusCode = resp.StatusCode
+	if ossError.RequestId == "" {
+		ossError.RequestId = resp.Header.Get("X-Oss-Request-Id")
+	}
+	return decision, ossError
+}
+
 type CompleteUploadFileRequest struct {
 	// 文件Id，必须
 	FileId string `json:"file_id"`
 	// 上传Id，必须
 	UploadId string `json:"upload_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type CompleteUploadFileResponse struct {
@@ -552,11 +783,11 @@ func (c *Drive) DoCompleteUploadFileRequest(ctx context.Context, request Complet
 		DriveId string `json:"drive_id"`
 		CompleteUploadFileRequest
 	}{
-		DriveId:                   c.driveId,
+		DriveId:                   c.requestDriveId(request.DriveId),
 		CompleteUploadFileRequest: request,
 	}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v2/file/complete", params)
+	resp, err := c.requestWithCredit(ctx, apiFileComplete, params)
 	if err != nil {
 		return nil, err
 	}
@@ -589,6 +820,10 @@ type RapidCreateFileRequest struct {
 	// 所以无法再使用内部token管理器内的accesstoken，
 	// 需要人为指定参与计算proofcode的accesstoken
 	AccessToken string `json:"-"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 同名冲突策略，可选，为空则使用NameConflictAutoRename
+	NameConflictPolicy NameConflictPolicy `json:"-"`
 }
 
 type RapidCreateFileResponse struct {
@@ -605,11 +840,16 @@ type RapidCreateFileResponse struct {
 	EncryptMode string `json:"encrypt_mode"`
 	// 上传Id
 	UploadId string `json:"upload_id"`
+	// 是否已存在同名的文件/目录，为true时返回的是已存在的
+	Exist bool `json:"exist"`
+	// 同名冲突的处理结果
+	NameConflict NameConflictResult `json:"-"`
 }
 
 // 秒传文件接口
 //
-// 在创建文件接口返回PreHashMatched错误后，可调用该接口秒传文件
+// 在创建文件接口返回PreHashMatched错误后，可调用该接口秒传文件；
+// 覆盖模式下会先查询一次是否有同名文件
 func (c *Drive) DoRapidCreateFileRequest(ctx context.Context, request RapidCreateFileRequest) (*RapidCreateFileResponse, error) {
 	params := &struct {
 		DriveId         string `json:"drive_id"`
@@ -622,8 +862,8 @@ func (c *Drive) DoRapidCreateFileRequest(ctx context.Context, request RapidCreat
 		PartInfoList    Array  `json:"part_info_list"`
 		RapidCreateFileRequest
 	}{
-		DriveId:                c.driveId,
-		CheckNameMode:          "auto_rename",
+		DriveId:                c.requestDriveId(request.DriveId),
+		CheckNameMode:          string(request.NameConflictPolicy.or(NameConflictAutoRename)),
 		CreateScene:            "file_upload",
 		ContentHashName:        "sha1",
 		Type:                   "file",
@@ -646,12 +886,16 @@ func (c *Drive) DoRapidCreateFileRequest(ctx context.Context, request RapidCreat
 		params.PartInfoList[i] = Object{"part_number": i}
 	}
 
-	httpRequest, err := c.toRequest(ctx, "https://api.aliyundrive.com/adrive/v2/file/createWithFolders", params)
+	overwritten, err := c.nameExists(ctx, request.NameConflictPolicy, params.DriveId, request.ParentFileId, request.Name)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := c.toRequest(ctx, apiFileCreateWithFolders, params)
 	if err != nil {
 		return nil, err
 	}
 	httpRequest.Header.Set("Authorization", "Bearer "+params.AccessToken)
-	resp, err := c.doRequest(httpRequest)
+	resp, err := c.doRequest(apiFileCreateWithFolders, params, httpRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -661,6 +905,7 @@ func (c *Drive) DoRapidCreateFileRequest(ctx context.Context, request RapidCreat
 	if err != nil {
 		return nil, err
 	}
+	result.NameConflict = nameConflictResult(request.Name, result.FileName, result.Exist, overwritten)
 	return result, nil
 }
 
@@ -669,25 +914,34 @@ type RenameRequest struct {
 	FileId string `json:"file_id"`
 	// 文件名，必须
 	Name string `json:"name"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 同名冲突策略，可选，为空则使用NameConflictRefuse，即有同名时返回AlreadyExist错误，
+	// 重命名不支持NameConflictOverwrite
+	NameConflictPolicy NameConflictPolicy `json:"-"`
 }
 
 type RenameResponse struct {
 	Item
+	// 同名冲突的处理结果
+	NameConflict NameConflictResult `json:"-"`
 }
 
-// 重命名文件接口
-func (c *Drive) DoRenameRequest(ctx context.Context, request RenameRequest) (*RenameResponse, error) {
-	params := &struct {
+func (c *Drive) renameParams(request RenameRequest) any {
+	return &struct {
 		DriveId       string `json:"drive_id"`
 		CheckNameMode string `json:"check_name_mode"`
 		RenameRequest
 	}{
-		DriveId:       c.driveId,
-		CheckNameMode: "refuse",
+		DriveId:       c.requestDriveId(request.DriveId),
+		CheckNameMode: string(request.NameConflictPolicy.or(NameConflictRefuse)),
 		RenameRequest: request,
 	}
+}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v3/file/update", params)
+// 重命名文件接口
+func (c *Drive) DoRenameRequest(ctx context.Context, request RenameRequest) (*RenameResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiFileUpdate, c.renameParams(request))
 	if err != nil {
 		return nil, err
 	}
@@ -697,6 +951,7 @@ func (c *Drive) DoRenameRequest(ctx context.Context, request RenameRequest) (*Re
 	if err != nil {
 		return nil, err
 	}
+	result.NameConflict = nameConflictResult(request.Name, result.Name, false, false)
 	return result, nil
 }
 
@@ -705,25 +960,31 @@ type MoveRequest struct {
 	FileId string `json:"file_id"`
 	// 目的目录文件Id，必须
 	ToParentFileId string `json:"to_parent_file_id"`
+	// 目的网盘Id，可选，为空则与DriveId相同，不同时为跨网盘移动
+	ToDriveId string `json:"-"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type MoveResponse struct {
 	FileId string `json:"file_id"`
 }
 
-// 移动文件/目录接口
-func (c *Drive) DoMoveRequest(ctx context.Context, request MoveRequest) (*MoveResponse, error) {
-	params := &struct {
+func (c *Drive) moveParams(request MoveRequest) any {
+	return &struct {
 		DriveId   string `json:"drive_id"`
 		ToDriveId string `json:"to_drive_id"`
 		MoveRequest
 	}{
-		DriveId:     c.driveId,
-		ToDriveId:   c.driveId,
+		DriveId:     c.requestDriveId(request.DriveId),
+		ToDriveId:   c.requestDriveId(request.ToDriveId, request.DriveId),
 		MoveRequest: request,
 	}
+}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v3/file/move", params)
+// 移动文件/目录接口
+func (c *Drive) DoMoveRequest(ctx context.Context, request MoveRequest) (*MoveResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiFileMove, c.moveParams(request))
 	if err != nil {
 		return nil, err
 	}
@@ -736,9 +997,72 @@ func (c *Drive) DoMoveRequest(ctx context.Context, request MoveRequest) (*MoveRe
 	return result, nil
 }
 
+type CopyRequest struct {
+	// 文件Id，必须
+	FileId string `json:"file_id"`
+	// 目的目录文件Id，必须
+	ToParentFileId string `json:"to_parent_file_id"`
+	// 复制后的文件名，可选，为空则使用原来的文件名
+	NewName string `json:"new_name,omitempty"`
+	// 目的目录下有同名文件/目录时是否自动重命名，可选，为false时返回AlreadyExist错误
+	AutoRename bool `json:"auto_rename"`
+	// 目的网盘Id，可选，为空则与DriveId相同
+	ToDriveId string `json:"-"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 是否等待异步任务完成之后再返回，可选
+	Wait bool `json:"-"`
+}
+
+type CopyResponse struct {
+	// 复制出的文件/目录所在的网盘Id
+	DriveId string `json:"drive_id"`
+	// 复制出的文件/目录Id
+	FileId string `json:"file_id"`
+	// 异步任务Id，复制较大的目录时才有，任务完成之前目录的内容可能不完整
+	AsyncTaskId string `json:"async_task_id"`
+	// 异步任务的最终状态，设置了Wait并且有异步任务时才有
+	AsyncTask *GetAsyncTaskResponse `json:"-"`
+}
+
+// 复制文件/目录接口，复制在服务端完成，不需要下载和上传数据
+//
+// 设置了Wait时，等待失败（如异步任务失败）也会返回结果，其中AsyncTask为任务的最终状态
+func (c *Drive) DoCopyRequest(ctx context.Context, request CopyRequest) (*CopyResponse, error) {
+	params := &struct {
+		DriveId   string `json:"drive_id"`
+		ToDriveId string `json:"to_drive_id"`
+		CopyRequest
+	}{
+		DriveId:     c.requestDriveId(request.DriveId),
+		ToDriveId:   c.requestDriveId(request.ToDriveId, request.DriveId),
+		CopyRequest: request,
+	}
+
+	resp, err := c.requestWithCredit(ctx, apiFileCopy, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(CopyResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	result.AsyncTask, err = c.waitAsyncTask(ctx, request.Wait, result.AsyncTaskId)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
 type TrashRequest struct {
 	// 文件Id，必须
 	FileId string `json:"file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 是否等待异步任务完成之后再返回，可选
+	Wait bool `json:"-"`
 }
 
 type TrashResponse struct {
@@ -746,19 +1070,25 @@ type TrashResponse struct {
 	AsyncTaskId string `json:"async_task_id"`
 	// 文件Id
 	FileId string `json:"file_id"`
+	// 异步任务的最终状态，设置了Wait并且有异步任务时才有
+	AsyncTask *GetAsyncTaskResponse `json:"-"`
 }
 
-// 文件/目录移到回收站接口
-func (c *Drive) DoTrashRequest(ctx context.Context, request TrashRequest) (*TrashResponse, error) {
-	params := &struct {
+func (c *Drive) trashParams(request TrashRequest) any {
+	return &struct {
 		DriveId string `json:"drive_id"`
 		TrashRequest
 	}{
-		DriveId:      c.driveId,
+		DriveId:      c.requestDriveId(request.DriveId),
 		TrashRequest: request,
 	}
+}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v2/recyclebin/trash", params)
+// 文件/目录移到回收站接口
+//
+// 设置了Wait时，等待失败（如异步任务失败）也会返回结果，其中AsyncTask为任务的最终状态
+func (c *Drive) DoTrashRequest(ctx context.Context, request TrashRequest) (*TrashResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiRecyclebinTrash, c.trashParams(request))
 	if err != nil {
 		return nil, err
 	}
@@ -768,29 +1098,42 @@ func (c *Drive) DoTrashRequest(ctx context.Context, request TrashRequest) (*Tras
 	if err != nil {
 		return nil, err
 	}
+	result.AsyncTask, err = c.waitAsyncTask(ctx, request.Wait, result.AsyncTaskId)
+	if err != nil {
+		return result, err
+	}
 	return result, nil
 }
 
-type ClearTrashRequest struct{}
+type ClearTrashRequest struct {
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 是否等待异步任务完成之后再返回，可选
+	Wait bool `json:"-"`
+}
 
 type ClearTrashResponse struct {
 	// 异步任务Id
 	AsyncTaskId string `json:"async_task_id"`
 	// 任务Id
 	TaskId string `json:"task_id"`
+	// 异步任务的最终状态，设置了Wait并且有异步任务时才有
+	AsyncTask *GetAsyncTaskResponse `json:"-"`
 }
 
 // 清空回收站接口
+//
+// 设置了Wait时，等待失败（如异步任务失败）也会返回结果，其中AsyncTask为任务的最终状态
 func (c *Drive) DoClearTrashRequest(ctx context.Context, request ClearTrashRequest) (*ClearTrashResponse, error) {
 	params := &struct {
 		DriveId string `json:"drive_id"`
 		ClearTrashRequest
 	}{
-		DriveId:           c.driveId,
+		DriveId:           c.requestDriveId(request.DriveId),
 		ClearTrashRequest: request,
 	}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/v2/recyclebin/clear", params)
+	resp, err := c.requestWithCredit(ctx, apiRecyclebinClear, params)
 	if err != nil {
 		return nil, err
 	}
@@ -800,6 +1143,14 @@ func (c *Drive) DoClearTrashRequest(ctx context.Context, request ClearTrashReque
 	if err != nil {
 		return nil, err
 	}
+	asyncTaskId := result.AsyncTaskId
+	if asyncTaskId == "" {
+		asyncTaskId = result.TaskId
+	}
+	result.AsyncTask, err = c.waitAsyncTask(ctx, request.Wait, asyncTaskId)
+	if err != nil {
+		return result, err
+	}
 	return result, nil
 }
 
@@ -812,6 +1163,8 @@ type ListTrashRequest struct {
 	Limit int `json:"limit,omitempty"`
 	// 分页标记，可选
 	NextMarker string `json:"marker,omitempty"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type ListTrashResponse struct {
@@ -827,11 +1180,11 @@ func (c *Drive) DoListTrashRequest(ctx context.Context, request ListTrashRequest
 		DriveId string `json:"drive_id"`
 		ListTrashRequest
 	}{
-		DriveId:          c.driveId,
+		DriveId:          c.requestDriveId(request.DriveId),
 		ListTrashRequest: request,
 	}
 
-	resp, err := c.requestWithCredit(ctx, "https://api.aliyundrive.com/adrive/v2/recyclebin/list", params)
+	resp, err := c.requestWithCredit(ctx, apiRecyclebinList, params)
 	if err != nil {
 		return nil, err
 	}
@@ -847,6 +1200,8 @@ func (c *Drive) DoListTrashRequest(ctx context.Context, request ListTrashRequest
 type RestoreRequest struct {
 	// 文件Id，必须
 	FileId string `json:"file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
 }
 
 type RestoreResponse struct {
@@ -858,25 +1213,11 @@ func (c *Drive) DoRestoreRequest(ctx context.Context, request RestoreRequest) (*
 		DriveId string `json:"drive_id"`
 		RestoreRequest
 	}{
-		DriveId:        c.driveId,
+		DriveId:        c.requestDriveId(request.DriveId),
 		RestoreRequest: request,
 	}
 
-	accessToken, err := c.tokenManager.AccessToken(ctx)
-	if err != nil {
-		return nil, err
-	}
-	httpRequest, err := c.toRequest(ctx, "https://api.aliyundrive.com/v2/recyclebin/restore", params)
-	if err != nil {
-		return nil, err
-	}
-	httpRequest.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := c.HttpClient.Do(httpRequest)
-	if err != nil {
-		return nil, err
-	}
-	_, err = io.Copy(io.Discard, resp.Body)
-	resp.Body.Close()
+	_, err := c.requestWithCredit(ctx, apiRecyclebinRestore, params)
 	if err != nil {
 		return nil, err
 	}
@@ -886,38 +1227,49 @@ func (c *Drive) DoRestoreRequest(ctx context.Context, request RestoreRequest) (*
 type DeleteRequest struct {
 	// 文件Id，必须
 	FileId string `json:"file_id"`
+	// 网盘Id，可选，为空则使用Drive当前的网盘Id
+	DriveId string `json:"-"`
+	// 是否等待异步任务完成之后再返回，可选
+	Wait bool `json:"-"`
 }
 
 type DeleteResponse struct {
+	// 异步任务Id，删除目录等耗时较长时才有
+	AsyncTaskId string `json:"async_task_id"`
+	// 异步任务的最终状态，设置了Wait并且有异步任务时才有
+	AsyncTask *GetAsyncTaskResponse `json:"-"`
 }
 
-// 永久删除文件/目录接口（不管是否在回收站）
-func (c *Drive) DoDeleteRequest(ctx context.Context, request DeleteRequest) (*DeleteResponse, error) {
-	params := &struct {
+func (c *Drive) deleteParams(request DeleteRequest) any {
+	return &struct {
 		DriveId string `json:"drive_id"`
 		DeleteRequest
 	}{
-		DriveId:       c.driveId,
+		DriveId:       c.requestDriveId(request.DriveId),
 		DeleteRequest: request,
 	}
+}
 
-	accessToken, err := c.tokenManager.AccessToken(ctx)
-	if err != nil {
-		return nil, err
-	}
-	httpRequest, err := c.toRequest(ctx, "https://api.aliyundrive.com/v3/file/delete", params)
+// 永久删除文件/目录接口（不管是否在回收站）
+//
+// 设置了Wait时，等待失败（如异步任务失败）也会返回结果，其中AsyncTask为任务的最终状态
+func (c *Drive) DoDeleteRequest(ctx context.Context, request DeleteRequest) (*DeleteResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiFileDelete, c.deleteParams(request))
 	if err != nil {
 		return nil, err
 	}
-	httpRequest.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := c.HttpClient.Do(httpRequest)
-	if err != nil {
-		return nil, err
+
+	// 同步删除完成时没有返回内容
+	result := new(DeleteResponse)
+	if len(resp) > 0 {
+		err = json.Unmarshal(resp, result)
+		if err != nil {
+			return nil, err
+		}
 	}
-	_, err = io.Copy(io.Discard, resp.Body)
-	resp.Body.Close()
+	result.AsyncTask, err = c.waitAsyncTask(ctx, request.Wait, result.AsyncTaskId)
 	if err != nil {
-		return nil, err
+		return result, err
 	}
-	return &DeleteResponse{}, nil
+	return result, nil
 }
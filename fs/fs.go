@@ -2,6 +2,7 @@ package fs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -105,48 +106,26 @@ func (f *Fs) walkDir(ctx context.Context, rootPath string, rootFile *File, fn fs
 	return nil
 }
 
+// 根据路径打开文件/目录，根目录之外的路径通过get_by_path一次定位
 func (f *Fs) open(ctx context.Context, p string) (*File, error) {
-	root, err := f.c.DoGetRequest(ctx,
-		aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
-	if err != nil {
-		return nil, err
-	}
-
-	p = path.Join(f.root, p)
-	paths := splitPath(p)
-	file := &File{fs: f, item: &root.Item}
-
-	for _, name := range paths {
-		if name == "/" {
-			continue
-		}
-
-		next := ""
-		var targetItem *aliyundrive.Item
-		for {
-			var items []*aliyundrive.Item
-			items, next, err = file.list(ctx, aliyundrive.LimitMax, next)
-			if err != nil {
-				return nil, err
-			}
-			for _, item := range items {
-				if item.Name == name {
-					targetItem = item
-					break
-				}
-			}
-			if targetItem != nil || next == "" {
-				break
-			}
+	p = path.Join("/", f.root, p)
+	if p == "/" {
+		root, err := f.c.DoGetRequest(ctx,
+			aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
+		if err != nil {
+			return nil, err
 		}
+		return &File{fs: f, item: &root.Item}, nil
+	}
 
-		if targetItem == nil {
+	resp, err := f.c.DoGetByPathRequest(ctx, aliyundrive.GetByPathRequest{FilePath: p})
+	if err != nil {
+		if errors.Is(err, aliyundrive.ErrNotFound) {
 			return nil, fs.ErrNotExist
 		}
-		file = &File{fs: f, item: targetItem}
+		return nil, err
 	}
-
-	return file, nil
+	return &File{fs: f, item: &resp.Item}, nil
 }
 
 type File struct {
@@ -292,8 +271,9 @@ func (f *File) close() error {
 	if f.body == nil {
 		return nil
 	}
+	// 先取消请求再关闭，未读完的数据不再下载，
+	// 取消后连接不会被复用，也就没有必要读完剩余的数据
 	f.cancel()
-	io.Copy(io.Discard, f.body)
 	err := f.body.Close()
 	f.cancel = nil
 	f.body = nil
@@ -321,14 +301,3 @@ func (f *File) list(ctx context.Context, limit int, next string) (items []*aliyu
 	items = resp.Items
 	return
 }
-
-func splitPath(p string) []string {
-	p = path.Clean(p)
-	if p == "." || p == "/" {
-		return []string{"/"}
-	}
-
-	dir, file := path.Split(p)
-	parents := splitPath(dir)
-	return append(parents, file)
-}
@@ -0,0 +1,126 @@
+package fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+	"github.com/xbugio/aliyundrive-go-sdk/aliyundrivetest"
+	alifs "github.com/xbugio/aliyundrive-go-sdk/fs"
+)
+
+// 创建带有docs/a.txt、docs/sub/b.txt、c.txt的模拟服务，返回以根目录为根的文件系统
+func newTestFs(t *testing.T) *alifs.Fs {
+	t.Helper()
+	srv := aliyundrivetest.NewServer()
+	t.Cleanup(srv.Close)
+	docs := srv.AddFolder(aliyundrive.RootFileId, "docs")
+	srv.AddFile(docs.FileId, "a.txt", []byte("hello"))
+	sub := srv.AddFolder(docs.FileId, "sub")
+	srv.AddFile(sub.FileId, "b.txt", []byte("world"))
+	srv.AddFile(aliyundrive.RootFileId, "c.txt", nil)
+
+	c, err := srv.NewDrive()
+	if err != nil {
+		t.Fatalf("new drive: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return alifs.New(c, "/")
+}
+
+func TestOpen(t *testing.T) {
+	fsys := newTestFs(t)
+
+	file, err := fsys.Open("docs/sub/b.txt")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Name() != "b.txt" || info.IsDir() || info.Size() != 5 {
+		t.Fatalf("stat = %s dir=%v size=%d, want b.txt file of 5 bytes", info.Name(), info.IsDir(), info.Size())
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != "world" {
+		t.Fatalf("read %q, want %q", data, "world")
+	}
+
+	for _, name := range []string{"missing.txt", "docs/missing/b.txt"} {
+		if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("open %s error = %v, want fs.ErrNotExist", name, err)
+		}
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	fsys := newTestFs(t)
+
+	entries, err := fsys.ReadDir("docs")
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	// 根目录以外的目录第一项为指向上级目录的..
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+		if entry.IsDir() != (entry.Name() != "a.txt") {
+			t.Errorf("%s IsDir = %v", entry.Name(), entry.IsDir())
+		}
+	}
+	sort.Strings(names)
+	if len(names) != 3 || names[0] != ".." || names[1] != "a.txt" || names[2] != "sub" {
+		t.Fatalf("read dir names = %v, want [.. a.txt sub]", names)
+	}
+
+	entries, err = fsys.ReadDir(".")
+	if err != nil {
+		t.Fatalf("read root dir: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "c.txt" || entries[1].Name() != "docs" {
+		t.Fatalf("read root dir = %v, want [c.txt docs]", entries)
+	}
+
+	if _, err := fsys.ReadDir("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("read missing dir error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+// example/http.go的用法，通过http.FileServer提供文件下载
+func TestFileServer(t *testing.T) {
+	fsys := newTestFs(t)
+	server := httptest.NewServer(http.FileServer(http.FS(fsys)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/docs/a.txt")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(data) != "hello" {
+		t.Fatalf("get /docs/a.txt = %d %q, want 200 %q", resp.StatusCode, data, "hello")
+	}
+
+	resp, err = http.Get(server.URL + "/missing.txt")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("get /missing.txt = %d, want 404", resp.StatusCode)
+	}
+}
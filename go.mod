@@ -1,5 +1,5 @@
 module github.com/xbugio/aliyundrive-go-sdk
 
-go 1.19
+go 1.21
 
 require github.com/dustinxie/ecc v0.0.0-20210511000915-959544187564
@@ -0,0 +1,106 @@
+package aliyundrive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// 下载文件数据的调用名
+	OperationDownload = "download"
+	// 上传文件数据的调用名
+	OperationUpload = "upload"
+)
+
+// 一次接口调用（的一次尝试）的信息
+type Call struct {
+	// 调用名，如file/list、user/get，上传/下载数据时为OperationUpload、OperationDownload
+	Operation string
+	// 请求参数，为实际提交的参数或者上传/下载数据的请求结构体
+	Params any
+	// 第几次尝试，从1开始，重试时递增
+	Attempt int
+	// 将要发送的http请求，拦截器可以修改请求头，也可以替换为新的请求
+	Request *http.Request
+	// 收到的http响应，调用next之后才有
+	//
+	// 接口调用的响应体已经读取并关闭，内容在ResponseData中；
+	// 上传/下载数据的响应体为数据流，拦截器不能读取
+	Response *http.Response
+	// 接口调用的响应体数据，上传/下载数据时为空
+	ResponseData []byte
+}
+
+// 调用处理函数
+//
+// 请求使用传入的ctx发送，拦截器可以传入新的ctx（如带超时）来控制本次尝试；
+// 返回的错误为云盘接口的*ErrorResponse、OSS的*OssError或者网络错误等
+type Handler func(ctx context.Context, call *Call) error
+
+// 调用拦截器
+//
+// 拦截器可以在调用next前后做额外的处理，比如日志、监控、注入请求头，
+// 也可以不调用next直接返回错误来模拟故障，
+// 返回的*ErrorResponse、*OssError会和服务端返回的一样参与重试判断
+type Interceptor func(ctx context.Context, call *Call, next Handler) error
+
+// 经过拦截器链执行一次调用，Interceptors中排在前面的拦截器在最外层
+func (c *Drive) intercept(ctx context.Context, call *Call, handler Handler) error {
+	for i := len(c.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.Interceptors[i], handler
+		handler = func(ctx context.Context, call *Call) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return handler(ctx, call)
+}
+
+// 经过拦截器链执行一次调用，once为实际发送请求的函数
+//
+// 若拦截器没有调用next而直接返回错误，则根据错误本身判断是否重试
+func (c *Drive) doCall(call *Call, once func(call *Call) (retryDecision, error)) (retryDecision, error) {
+	var (
+		decision retryDecision
+		handled  bool
+	)
+	start := time.Now()
+	err := c.intercept(call.Request.Context(), call, func(ctx context.Context, call *Call) error {
+		handled = true
+		if ctx != call.Request.Context() {
+			call.Request = call.Request.WithContext(ctx)
+		}
+		var err error
+		decision, err = once(call)
+		return err
+	})
+	latency := time.Since(start)
+	if err != nil && !handled {
+		decision = decideByError(err)
+	}
+
+	c.metrics().ObserveRequest(call.Operation, latency, err)
+	attrs := []any{"operation", call.Operation, "attempt", call.Attempt, "latency", latency}
+	if err != nil {
+		c.logger().Warn("aliyundrive: request failed", append(attrs, errorAttrs(err)...)...)
+	} else {
+		c.logger().Debug("aliyundrive: request", attrs...)
+	}
+	return decision, err
+}
+
+// 根据错误判断是否需要重试，用于没有实际发送请求的情况
+func decideByError(err error) retryDecision {
+	var d retryDecision
+	var errResponse *ErrorResponse
+	var ossError *OssError
+	switch {
+	case errors.As(err, &errResponse):
+		d.temporary = errResponse.StatusCode >= 500
+	case errors.As(err, &ossError):
+		d.temporary = ossError.StatusCode >= 500
+	}
+	d.throttled = errors.Is(err, ErrTooManyRequests)
+	return d
+}
@@ -0,0 +1,30 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+func TestInterceptorContext(t *testing.T) {
+	_, c := newTestDrive(t,
+		aliyundrive.WithRetryPolicy(aliyundrive.RetryPolicy{MaxAttempts: 1}),
+		aliyundrive.WithInterceptors(func(ctx context.Context, call *aliyundrive.Call, next aliyundrive.Handler) error {
+			if call.Operation != "file/get" {
+				return next(ctx, call)
+			}
+			ctx, cancel := context.WithTimeout(ctx, time.Nanosecond)
+			defer cancel()
+			<-ctx.Done()
+			return next(ctx, call)
+		}),
+	)
+
+	_, err := c.DoGetRequest(context.Background(), aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("get with interceptor timeout error = %v, want context.DeadlineExceeded", err)
+	}
+}
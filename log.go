@@ -0,0 +1,64 @@
+package aliyundrive
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// 丢弃所有日志的Handler，Drive未设置Logger时使用
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+func (c *Drive) logger() *slog.Logger {
+	if c.Logger == nil {
+		return discardLogger
+	}
+	return c.Logger
+}
+
+// 隐藏token，只保留开头几位用于区分
+func redact(token string) string {
+	if len(token) <= 8 {
+		return "***"
+	}
+	return token[:4] + "***"
+}
+
+// 去掉错误中请求地址的查询参数
+//
+// 上传/下载地址的签名在查询参数中，http请求失败时*url.Error会带上完整地址
+func redactError(err error) error {
+	var urlError *url.Error
+	if !errors.As(err, &urlError) {
+		return err
+	}
+	u, parseErr := url.Parse(urlError.URL)
+	if parseErr != nil || u.RawQuery == "" {
+		return err
+	}
+	u.RawQuery = ""
+	return errors.New(strings.ReplaceAll(err.Error(), urlError.URL, u.String()))
+}
+
+// 错误相关的日志字段
+func errorAttrs(err error) []any {
+	attrs := []any{"error", redactError(err)}
+	var errResponse *ErrorResponse
+	var ossError *OssError
+	switch {
+	case errors.As(err, &errResponse):
+		attrs = append(attrs, "code", errResponse.Code, "status", errResponse.StatusCode, "request_id", errResponse.RequestId)
+	case errors.As(err, &ossError):
+		attrs = append(attrs, "code", ossError.Code, "status", ossError.StatusCode, "request_id", ossError.RequestId)
+	}
+	return attrs
+}
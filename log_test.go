@@ -0,0 +1,60 @@
+package aliyundrive_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 上传/下载数据时连接失败的Transport
+type brokenDataTransport struct {
+	http.RoundTripper
+}
+
+func (t brokenDataTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(r.URL.Path, "/upload/") || strings.HasPrefix(r.URL.Path, "/download/") {
+		return nil, errors.New("connection reset by peer")
+	}
+	return t.RoundTripper.RoundTrip(r)
+}
+
+func TestLogRedactsSignedUrl(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(testRetryPolicy), aliyundrive.WithLogger(logger))
+	c.HttpClient = &http.Client{Transport: brokenDataTransport{RoundTripper: srv.Client().Transport}}
+	ctx := context.Background()
+
+	created, err := c.DoCreateFileRequest(ctx, aliyundrive.CreateFileRequest{Name: "a.txt", ParentFileId: aliyundrive.RootFileId, Size: 5})
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	_, err = c.DoUploadFileRequest(ctx, aliyundrive.UploadFileRequest{Url: created.PartInfoList[0].UploadUrl, File: bytes.NewReader([]byte("hello"))})
+	if err == nil {
+		t.Fatal("upload through broken transport succeeded")
+	}
+	file := srv.AddFile(aliyundrive.RootFileId, "b.txt", []byte("hello"))
+	url, err := c.DoGetDownloadUrlRequest(ctx, aliyundrive.GetDownloadUrlRequest{FileId: file.FileId})
+	if err != nil {
+		t.Fatalf("get download url: %v", err)
+	}
+	if _, err := c.DoDownloadFileRequest(ctx, aliyundrive.DownloadFileRequest{Url: url.Url}); err == nil {
+		t.Fatal("download through broken transport succeeded")
+	}
+
+	logs := output.String()
+	for _, want := range []string{"retrying request", "request failed", "/upload/", "/download/", "connection reset by peer"} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("logs do not contain %q:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "x-oss-expires") {
+		t.Fatalf("logs contain the signed url query:\n%s", logs)
+	}
+}
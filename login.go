@@ -0,0 +1,266 @@
+package aliyundrive
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 二维码状态
+const (
+	// 等待扫码
+	QrCodeStatusNew = "NEW"
+	// 已扫码，等待确认
+	QrCodeStatusScanned = "SCANED"
+	// 已确认登录
+	QrCodeStatusConfirmed = "CONFIRMED"
+	// 二维码已过期
+	QrCodeStatusExpired = "EXPIRED"
+	// 用户取消了登录
+	QrCodeStatusCanceled = "CANCELED"
+)
+
+var (
+	// 二维码已过期，需要重新生成
+	ErrQrCodeExpired = errors.New("aliyundrive: qr code expired")
+	// 用户取消了扫码登录
+	ErrQrCodeCanceled = errors.New("aliyundrive: qr code login canceled")
+)
+
+// 登录接口的公共参数
+func passportQuery() url.Values {
+	query := url.Values{}
+	query.Set("appName", "aliyun_drive")
+	query.Set("fromSite", "52")
+	query.Set("appEntrance", "web")
+	query.Set("isMobile", "false")
+	query.Set("lang", "zh_CN")
+	query.Set("returnUrl", "")
+	query.Set("bizParams", "")
+	return query
+}
+
+// 解析登录接口的返回，data为content.data的内容
+func parsePassportResponse(resp []byte, data any) error {
+	result := &struct {
+		Content struct {
+			Data    json.RawMessage `json:"data"`
+			Success bool            `json:"success"`
+		} `json:"content"`
+		HasError bool `json:"hasError"`
+	}{}
+	if err := json.Unmarshal(resp, result); err != nil {
+		return err
+	}
+	if result.HasError || !result.Content.Success {
+		message := &struct {
+			TitleMsg string `json:"titleMsg"`
+		}{}
+		json.Unmarshal(result.Content.Data, message)
+		return fmt.Errorf("aliyundrive: passport request failed: %s", message.TitleMsg)
+	}
+	return json.Unmarshal(result.Content.Data, data)
+}
+
+type GenerateQrCodeRequest struct {
+}
+
+type GenerateQrCodeResponse struct {
+	// 二维码的内容，需要生成二维码展示给用户用阿里云盘App扫描
+	CodeContent string `json:"codeContent"`
+	// 查询二维码状态的参数
+	T int64 `json:"t"`
+	// 查询二维码状态的参数
+	Ck         string `json:"ck"`
+	ResultCode int    `json:"resultCode"`
+}
+
+// 生成扫码登录的二维码接口
+//
+// 该接口不需要accesstoken，可以在未初始化的Drive上调用
+func (c *Drive) DoGenerateQrCodeRequest(ctx context.Context, request GenerateQrCodeRequest) (*GenerateQrCodeResponse, error) {
+	apiUrl, err := c.apiUrl(apiQrCodeGenerate)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, "GET", apiUrl+"?"+passportQuery().Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doRequest(apiQrCodeGenerate, request, httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(GenerateQrCodeResponse)
+	err = parsePassportResponse(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type QueryQrCodeRequest struct {
+	// 生成二维码时返回的T，必须
+	T int64
+	// 生成二维码时返回的Ck，必须
+	Ck string
+}
+
+// 扫码登录的结果
+type QrLoginResult struct {
+	UserId         string `json:"userId"`
+	UserName       string `json:"userName"`
+	NickName       string `json:"nickName"`
+	Avatar         string `json:"avatar"`
+	Role           string `json:"role"`
+	DefaultDriveId string `json:"defaultDriveId"`
+	AccessToken    string `json:"accessToken"`
+	RefreshToken   string `json:"refreshToken"`
+	ExpiresIn      int64  `json:"expiresIn"`
+	TokenType      string `json:"tokenType"`
+}
+
+type QueryQrCodeResponse struct {
+	// 二维码状态，QrCodeStatusXXX
+	QrCodeStatus string `json:"qrCodeStatus"`
+	ResultCode   int    `json:"resultCode"`
+	// 确认登录之后返回的登录信息，base64编码
+	BizExt string `json:"bizExt"`
+	// 从BizExt中解析出的登录结果，确认登录之后才有
+	LoginResult *QrLoginResult `json:"-"`
+}
+
+// 查询二维码状态接口
+//
+// 该接口不需要accesstoken，可以在未初始化的Drive上调用
+func (c *Drive) DoQueryQrCodeRequest(ctx context.Context, request QueryQrCodeRequest) (*QueryQrCodeResponse, error) {
+	form := passportQuery()
+	form.Set("t", strconv.FormatInt(request.T, 10))
+	form.Set("ck", request.Ck)
+	form.Set("navlanguage", "zh-CN")
+	form.Set("navPlatform", "MacIntel")
+
+	apiUrl, err := c.apiUrl(apiQrCodeQuery)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, "POST", apiUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.doRequest(apiQrCodeQuery, request, httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(QueryQrCodeResponse)
+	err = parsePassportResponse(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	if result.QrCodeStatus == QrCodeStatusConfirmed && result.BizExt != "" {
+		bizExt, err := base64.StdEncoding.DecodeString(result.BizExt)
+		if err != nil {
+			return nil, err
+		}
+		ext := &struct {
+			PdsLoginResult *QrLoginResult `json:"pds_login_result"`
+		}{}
+		if err := json.Unmarshal(bizExt, ext); err != nil {
+			return nil, err
+		}
+		result.LoginResult = ext.PdsLoginResult
+	}
+	return result, nil
+}
+
+// 扫码登录的回调，都是可选的
+type QrLoginHandler struct {
+	// 查询二维码状态的间隔，为0则使用2秒
+	PollInterval time.Duration
+	// 生成二维码之后调用，需要将codeContent生成二维码展示给用户扫描
+	OnNew func(codeContent string)
+	// 用户扫码之后调用
+	OnScanned func()
+	// 用户确认登录之后调用
+	OnConfirmed func(result *QrLoginResult)
+	// 二维码过期之后调用
+	OnExpired func()
+}
+
+// 扫码登录
+//
+// 生成二维码并轮询状态，直到用户确认登录、二维码过期、用户取消或者ctx取消，
+// 状态变化时调用handler中对应的回调；
+// 过期时返回ErrQrCodeExpired，取消时返回ErrQrCodeCanceled。
+//
+// 登录成功后可以用结果中的RefreshToken创建Drive，该方法可以在未初始化的Drive上调用
+func (c *Drive) QrLogin(ctx context.Context, handler QrLoginHandler) (*QrLoginResult, error) {
+	qrCode, err := c.DoGenerateQrCodeRequest(ctx, GenerateQrCodeRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if handler.OnNew != nil {
+		handler.OnNew(qrCode.CodeContent)
+	}
+
+	interval := handler.PollInterval
+	if interval <= 0 {
+		interval = time.Second * 2
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	status := QrCodeStatusNew
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		resp, err := c.DoQueryQrCodeRequest(ctx, QueryQrCodeRequest{
+			T:  qrCode.T,
+			Ck: qrCode.Ck,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.QrCodeStatus == status {
+			continue
+		}
+		status = resp.QrCodeStatus
+
+		switch status {
+		case QrCodeStatusScanned:
+			if handler.OnScanned != nil {
+				handler.OnScanned()
+			}
+		case QrCodeStatusConfirmed:
+			if resp.LoginResult == nil {
+				return nil, errors.New("aliyundrive: qr code confirmed without login result")
+			}
+			c.logger().Info("aliyundrive: qr code login confirmed", "user_id", resp.LoginResult.UserId, "refresh_token", redact(resp.LoginResult.RefreshToken))
+			if handler.OnConfirmed != nil {
+				handler.OnConfirmed(resp.LoginResult)
+			}
+			return resp.LoginResult, nil
+		case QrCodeStatusExpired:
+			if handler.OnExpired != nil {
+				handler.OnExpired()
+			}
+			return nil, ErrQrCodeExpired
+		case QrCodeStatusCanceled:
+			return nil, ErrQrCodeCanceled
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+	"github.com/xbugio/aliyundrive-go-sdk/aliyundrivetest"
+)
+
+// 在未初始化的Drive上扫码登录，user在二维码生成和扫码之后模拟用户的操作，返回依次触发的回调
+func qrLogin(srv *aliyundrivetest.Server, user func(status string, codeContent string)) (*aliyundrive.QrLoginResult, []string, error) {
+	c := &aliyundrive.Drive{HttpClient: srv.Client(), Endpoint: srv.Endpoint()}
+	var codeContent string
+	var events []string
+	result, err := c.QrLogin(context.Background(), aliyundrive.QrLoginHandler{
+		PollInterval: time.Millisecond * 10,
+		OnNew: func(content string) {
+			codeContent = content
+			events = append(events, "new")
+			user(aliyundrive.QrCodeStatusNew, codeContent)
+		},
+		OnScanned: func() {
+			events = append(events, "scanned")
+			user(aliyundrive.QrCodeStatusScanned, codeContent)
+		},
+		OnConfirmed: func(result *aliyundrive.QrLoginResult) {
+			events = append(events, "confirmed")
+		},
+		OnExpired: func() {
+			events = append(events, "expired")
+		},
+	})
+	return result, events, err
+}
+
+func TestQrLoginConfirmed(t *testing.T) {
+	srv := aliyundrivetest.NewServer()
+	defer srv.Close()
+
+	result, events, err := qrLogin(srv, func(status string, codeContent string) {
+		if status == aliyundrive.QrCodeStatusNew && !srv.ScanQrCode(codeContent) {
+			t.Error("scan qr code failed")
+		}
+		if status == aliyundrive.QrCodeStatusScanned && !srv.ConfirmQrCode(codeContent) {
+			t.Error("confirm qr code failed")
+		}
+	})
+	if err != nil {
+		t.Fatalf("qr login: %v", err)
+	}
+	if want := []string{"new", "scanned", "confirmed"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("callbacks = %v, want %v", events, want)
+	}
+	if result.UserId != aliyundrivetest.UserId || result.RefreshToken == "" {
+		t.Fatalf("login result = %+v", result)
+	}
+
+	// 用登录得到的refresh token创建Drive
+	c, err := srv.NewDrive(aliyundrive.WithRefreshToken(result.RefreshToken))
+	if err != nil {
+		t.Fatalf("new drive with login refresh token: %v", err)
+	}
+	c.Close()
+}
+
+func TestQrLoginExpired(t *testing.T) {
+	srv := aliyundrivetest.NewServer()
+	defer srv.Close()
+
+	_, events, err := qrLogin(srv, func(status string, codeContent string) {
+		if !srv.ExpireQrCode(codeContent) {
+			t.Error("expire qr code failed")
+		}
+	})
+	if !errors.Is(err, aliyundrive.ErrQrCodeExpired) {
+		t.Fatalf("qr login error = %v, want ErrQrCodeExpired", err)
+	}
+	if want := []string{"new", "expired"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("callbacks = %v, want %v", events, want)
+	}
+}
+
+func TestQrLoginCanceled(t *testing.T) {
+	srv := aliyundrivetest.NewServer()
+	defer srv.Close()
+
+	_, events, err := qrLogin(srv, func(status string, codeContent string) {
+		if status == aliyundrive.QrCodeStatusNew && !srv.ScanQrCode(codeContent) {
+			t.Error("scan qr code failed")
+		}
+		if status == aliyundrive.QrCodeStatusScanned && !srv.CancelQrCode(codeContent) {
+			t.Error("cancel qr code failed")
+		}
+	})
+	if !errors.Is(err, aliyundrive.ErrQrCodeCanceled) {
+		t.Fatalf("qr login error = %v, want ErrQrCodeCanceled", err)
+	}
+	if want := []string{"new", "scanned"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("callbacks = %v, want %v", events, want)
+	}
+}
@@ -0,0 +1,149 @@
+package aliyundrive
+
+import (
+	"expvar"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 监控指标收集接口，实现需要并发安全
+type Metrics interface {
+	// 记录一次接口调用（的一次尝试），operation为调用名，err为空表示成功
+	//
+	// 失败时可以用ErrorCode获取云盘接口或者OSS的错误码
+	ObserveRequest(operation string, latency time.Duration, err error)
+	// 记录上传的数据字节数，失败重试时重新上传的数据也会计入
+	AddUploadBytes(n int64)
+	// 记录下载的数据字节数
+	AddDownloadBytes(n int64)
+	// 记录一次accesstoken刷新，err为空表示成功
+	ObserveTokenRefresh(err error)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveRequest(string, time.Duration, error) {}
+func (noopMetrics) AddUploadBytes(int64)                        {}
+func (noopMetrics) AddDownloadBytes(int64)                      {}
+func (noopMetrics) ObserveTokenRefresh(error)                   {}
+
+func (c *Drive) metrics() Metrics {
+	if c.Metrics == nil {
+		return noopMetrics{}
+	}
+	return c.Metrics
+}
+
+// 延迟统计的分桶上限，单位毫秒
+var latencyBuckets = []int64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+type expvarMetrics struct {
+	// 调用名 -> 调用次数
+	requests *expvar.Map
+	// 调用名 -> 失败次数
+	requestErrors *expvar.Map
+	// 错误码 -> 失败次数，没有错误码的错误（如网络错误）记为unknown
+	errorCodes *expvar.Map
+	// 调用名 -> 延迟分桶（le_xxx为小于等于xxx毫秒的次数，另有count和sum_ms）
+	latency *expvar.Map
+	// success/failure -> 刷新次数
+	tokenRefreshes *expvar.Map
+	uploadBytes    *expvar.Int
+	downloadBytes  *expvar.Int
+	lock           *sync.Mutex
+}
+
+// 创建一个基于expvar的监控指标收集器
+//
+// 指标发布在expvar的name变量下，可以通过/debug/vars查看，
+// 和expvar.Publish一样，name重复时会panic
+func NewExpvarMetrics(name string) *expvarMetrics {
+	m := &expvarMetrics{
+		requests:       new(expvar.Map),
+		requestErrors:  new(expvar.Map),
+		errorCodes:     new(expvar.Map),
+		latency:        new(expvar.Map),
+		tokenRefreshes: new(expvar.Map),
+		uploadBytes:    new(expvar.Int),
+		downloadBytes:  new(expvar.Int),
+		lock:           new(sync.Mutex),
+	}
+	root := expvar.NewMap(name)
+	root.Set("requests", m.requests)
+	root.Set("request_errors", m.requestErrors)
+	root.Set("error_codes", m.errorCodes)
+	root.Set("latency", m.latency)
+	root.Set("token_refreshes", m.tokenRefreshes)
+	root.Set("upload_bytes", m.uploadBytes)
+	root.Set("download_bytes", m.downloadBytes)
+	return m
+}
+
+func (m *expvarMetrics) ObserveRequest(operation string, latency time.Duration, err error) {
+	m.requests.Add(operation, 1)
+	if err != nil {
+		m.requestErrors.Add(operation, 1)
+		code := ErrorCode(err)
+		if code == "" {
+			code = "unknown"
+		}
+		m.errorCodes.Add(code, 1)
+	}
+
+	buckets := m.latencyBuckets(operation)
+	ms := latency.Milliseconds()
+	for _, bucket := range latencyBuckets {
+		if ms <= bucket {
+			buckets.Add("le_"+strconv.FormatInt(bucket, 10), 1)
+		}
+	}
+	buckets.Add("count", 1)
+	buckets.Add("sum_ms", ms)
+}
+
+// 获取调用名对应的延迟分桶，不存在则创建
+func (m *expvarMetrics) latencyBuckets(operation string) *expvar.Map {
+	if buckets, ok := m.latency.Get(operation).(*expvar.Map); ok {
+		return buckets
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if buckets, ok := m.latency.Get(operation).(*expvar.Map); ok {
+		return buckets
+	}
+	buckets := new(expvar.Map)
+	m.latency.Set(operation, buckets)
+	return buckets
+}
+
+func (m *expvarMetrics) AddUploadBytes(n int64) {
+	m.uploadBytes.Add(n)
+}
+
+func (m *expvarMetrics) AddDownloadBytes(n int64) {
+	m.downloadBytes.Add(n)
+}
+
+func (m *expvarMetrics) ObserveTokenRefresh(err error) {
+	if err != nil {
+		m.tokenRefreshes.Add("failure", 1)
+	} else {
+		m.tokenRefreshes.Add("success", 1)
+	}
+}
+
+// 统计读取字节数的数据流
+type countingReadCloser struct {
+	io.ReadCloser
+	add func(n int64)
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.add(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,197 @@
+package aliyundrive
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 开放平台OAuth的授权方式
+const (
+	// 授权码模式，用户授权之后用回调得到的code换取token
+	GrantTypeAuthorizationCode = "authorization_code"
+	// 用refresh token刷新token
+	GrantTypeRefreshToken = "refresh_token"
+	// 客户端凭证模式，只使用应用的client_id和client_secret获取token
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// 开放平台OAuth配置
+type OAuthConfig struct {
+	// 开放平台应用的client_id，必须
+	ClientId string
+	// 开放平台应用的client_secret，必须
+	ClientSecret string
+	// 授权回调地址，授权码模式生成授权地址时使用
+	RedirectUri string
+	// 申请的授权范围，多个以逗号分隔，如user:base,file:all:read,file:all:write
+	Scope string
+	// 授权码，可选，授权码模式下第一次获取token时使用，之后使用返回的refresh token刷新
+	Code string
+	// refresh token，可选，设置后直接用其刷新token
+	//
+	// Code和RefreshToken都为空时使用客户端凭证模式
+	RefreshToken string
+}
+
+type OAuthAccessTokenRequest struct {
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// 授权方式，GrantTypeXXX
+	GrantType    string `json:"grant_type"`
+	Code         string `json:"code,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type OAuthAccessTokenResponse struct {
+	TokenType    string `json:"token_type"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// 开放平台获取accesstoken接口，该接口不需要accesstoken，可以在未初始化的Drive上调用
+func (c *Drive) DoOAuthAccessTokenRequest(ctx context.Context, request OAuthAccessTokenRequest) (*OAuthAccessTokenResponse, error) {
+	resp, err := c.requestWithoutCredit(ctx, apiOAuthAccessToken, request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(OAuthAccessTokenResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// 生成开放平台授权码模式的授权地址
+//
+// 用户在浏览器中打开该地址并同意授权后，会带着code和state跳转到config.RedirectUri，
+// 之后将code设置到OAuthConfig.Code即可获取token
+func (c *Drive) OAuthAuthorizeUrl(config OAuthConfig, state string) string {
+	query := url.Values{}
+	query.Set("client_id", config.ClientId)
+	query.Set("redirect_uri", config.RedirectUri)
+	query.Set("scope", config.Scope)
+	query.Set("response_type", "code")
+	query.Set("state", state)
+	return strings.TrimSuffix(c.Endpoint.withDefaults().OpenBase, "/") + "/oauth/authorize?" + query.Encode()
+}
+
+type oauthTokenManager struct {
+	drive                 *Drive
+	config                OAuthConfig
+	code                  string
+	refreshToken          string
+	accessToken           string
+	accessTokenExpireTime time.Time
+	store                 RefreshTokenStore
+	lock                  *sync.Mutex
+	// 轮换出的refresh token尚未保存成功，下次获取accesstoken时重试保存
+	unsaved bool
+}
+
+// 创建一个开放平台OAuth Token管理器
+//
+// 有refresh token时用其刷新accesstoken，否则有授权码时用授权码换取，
+// 都没有则使用客户端凭证模式；授权码只会使用一次，之后使用返回的refresh token刷新。
+//
+// 与RefreshToken管理器一样，只在获取accesstoken时判断是否需要刷新
+func NewOAuthTokenManager(drive *Drive, config OAuthConfig) *oauthTokenManager {
+	return &oauthTokenManager{
+		drive:                 drive,
+		config:                config,
+		code:                  config.Code,
+		refreshToken:          config.RefreshToken,
+		accessTokenExpireTime: time.Unix(0, 0),
+		lock:                  new(sync.Mutex),
+	}
+}
+
+// 创建一个带持久化存储的开放平台OAuth Token管理器
+//
+// store中保存有refresh token时优先使用，不再使用授权码，
+// 之后每次获取到新的refresh token都会保存到store中
+func NewStoredOAuthTokenManager(ctx context.Context, drive *Drive, config OAuthConfig, store RefreshTokenStore) (*oauthTokenManager, error) {
+	storedRefreshToken, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if storedRefreshToken != "" {
+		config.RefreshToken = storedRefreshToken
+		config.Code = ""
+	}
+	m := NewOAuthTokenManager(drive, config)
+	m.store = store
+	return m, nil
+}
+
+func (m *oauthTokenManager) AccessToken(ctx context.Context) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.unsaved {
+		m.unsaved = !saveRefreshToken(ctx, m.drive.logger(), m.store, m.refreshToken)
+	}
+
+	now := time.Now()
+	if now.Before(m.accessTokenExpireTime) {
+		return m.accessToken, nil
+	}
+
+	err := m.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	return m.accessToken, nil
+}
+
+// 丢弃被服务端拒绝的accesstoken，只有与当前缓存的一致时才会丢弃
+func (m *oauthTokenManager) Invalidate(accessToken string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.accessToken == accessToken {
+		m.accessTokenExpireTime = time.Unix(0, 0)
+	}
+}
+
+func (m *oauthTokenManager) refresh(ctx context.Context) error {
+	request := OAuthAccessTokenRequest{
+		ClientId:     m.config.ClientId,
+		ClientSecret: m.config.ClientSecret,
+	}
+	switch {
+	case m.refreshToken != "":
+		request.GrantType = GrantTypeRefreshToken
+		request.RefreshToken = m.refreshToken
+	case m.code != "":
+		request.GrantType = GrantTypeAuthorizationCode
+		request.Code = m.code
+	default:
+		request.GrantType = GrantTypeClientCredentials
+	}
+
+	now := time.Now()
+	logger := m.drive.logger()
+	resp, err := m.drive.DoOAuthAccessTokenRequest(ctx, request)
+	m.drive.metrics().ObserveTokenRefresh(err)
+	if err != nil {
+		logger.Error("aliyundrive: oauth access token failed", append([]any{"grant_type", request.GrantType, "refresh_token", redact(m.refreshToken)}, errorAttrs(err)...)...)
+		return err
+	}
+	m.code = ""
+	m.accessToken = resp.AccessToken
+	m.accessTokenExpireTime = now.Add(time.Second * time.Duration(resp.ExpiresIn-60))
+	logger.Info("aliyundrive: oauth access token refreshed", "grant_type", request.GrantType, "refresh_token", redact(resp.RefreshToken), "expires_in", resp.ExpiresIn)
+	if resp.RefreshToken == "" {
+		return nil
+	}
+	m.refreshToken = resp.RefreshToken
+	if m.store != nil {
+		m.unsaved = !saveRefreshToken(ctx, logger, m.store, resp.RefreshToken)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+	"github.com/xbugio/aliyundrive-go-sdk/aliyundrivetest"
+)
+
+// 创建使用开放平台OAuth连接到模拟服务的Drive
+func newOAuthDrive(srv *aliyundrivetest.Server, config aliyundrive.OAuthConfig, opts ...aliyundrive.Option) (*aliyundrive.Drive, error) {
+	opts = append([]aliyundrive.Option{
+		aliyundrive.WithHttpClient(srv.Client()),
+		aliyundrive.WithEndpoint(srv.Endpoint()),
+		aliyundrive.WithOAuth(config),
+	}, opts...)
+	return aliyundrive.New(context.Background(), opts...)
+}
+
+func TestOAuthAuthorizationCode(t *testing.T) {
+	srv := aliyundrivetest.NewServer()
+	defer srv.Close()
+	config := srv.OAuthConfig()
+	config.Code = srv.IssueOAuthCode()
+
+	c, err := newOAuthDrive(srv, config)
+	if err != nil {
+		t.Fatalf("new drive: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.DoListRequest(context.Background(), aliyundrive.ListRequest{ParentFileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	// 授权码已经换过token，之后用返回的refresh token刷新
+	srv.RevokeAccessTokens()
+	if _, err := c.DoListRequest(context.Background(), aliyundrive.ListRequest{ParentFileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("list after revoking access tokens: %v", err)
+	}
+}
+
+func TestOAuthCodeNotReplayed(t *testing.T) {
+	srv := aliyundrivetest.NewServer()
+	defer srv.Close()
+	config := srv.OAuthConfig()
+	config.Code = srv.IssueOAuthCode()
+	var attempts atomic.Int32
+	counts := map[string]*atomic.Int32{"oauth/access_token": &attempts}
+
+	// 授权码只能使用一次，5xx时请求可能已经被处理，不能重放
+	srv.InjectFault("/oauth/access_token", 1, 500, "InternalError")
+	_, err := newOAuthDrive(srv, config,
+		aliyundrive.WithRetryPolicy(aliyundrive.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}),
+		aliyundrive.WithInterceptors(countCalls(counts)),
+	)
+	var errResponse *aliyundrive.ErrorResponse
+	if !errors.As(err, &errResponse) || errResponse.StatusCode != 500 {
+		t.Fatalf("new drive with access token fault error = %v, want the 500", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("access token attempted %d times, want 1", got)
+	}
+}
@@ -0,0 +1,137 @@
+package aliyundrive
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// 创建Drive的选项
+type Option func(c *Drive)
+
+// 设置发送请求使用的http客户端，默认http.DefaultClient
+func WithHttpClient(httpClient *http.Client) Option {
+	return func(c *Drive) {
+		c.HttpClient = httpClient
+	}
+}
+
+// 设置refresh token，使用默认的Token管理器时必须
+func WithRefreshToken(refreshToken string) Option {
+	return func(c *Drive) {
+		c.RefreshToken = refreshToken
+	}
+}
+
+// 设置refresh token的持久化存储
+func WithRefreshTokenStore(store RefreshTokenStore) Option {
+	return func(c *Drive) {
+		c.RefreshTokenStore = store
+	}
+}
+
+// 设置Token管理器，设置后不再使用refresh token获取accesstoken
+func WithTokenManager(tokenManager TokenManager) Option {
+	return func(c *Drive) {
+		c.TokenManager = tokenManager
+	}
+}
+
+// 设置开放平台OAuth配置，设置后通过OAuth获取accesstoken并使用开放平台接口
+func WithOAuth(config OAuthConfig) Option {
+	return func(c *Drive) {
+		c.OAuth = &config
+	}
+}
+
+// 设置签名管理器
+func WithSignatureManager(signatureManager SignatureManager) Option {
+	return func(c *Drive) {
+		c.SignatureManager = signatureManager
+	}
+}
+
+// 设置使用的网盘Id，默认使用用户信息中的默认网盘
+//
+// 单个请求也可以通过请求的DriveId字段使用其他网盘
+func WithDriveId(driveId string) Option {
+	return func(c *Drive) {
+		c.driveId = driveId
+	}
+}
+
+// 设置设备Id，默认根据用户Id生成
+//
+// 默认的设备Id对同一账号是固定的，同一账号下同时运行多个程序时，
+// 需要为每个程序设置不同的设备Id，否则会互相把对方挤下线
+func WithDeviceId(deviceId string) Option {
+	return func(c *Drive) {
+		c.DeviceId = deviceId
+	}
+}
+
+// 设置设备名称和型号，会显示在账号的登录设备列表中
+func WithDeviceName(deviceName string, modelName string) Option {
+	return func(c *Drive) {
+		c.DeviceName = deviceName
+		c.ModelName = modelName
+	}
+}
+
+// 设置接口地址
+func WithEndpoint(endpoint Endpoint) Option {
+	return func(c *Drive) {
+		c.Endpoint = endpoint
+	}
+}
+
+// 设置请求失败时的重试策略
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Drive) {
+		c.RetryPolicy = &policy
+	}
+}
+
+// 设置默认Token管理器的保活间隔，默认10秒
+//
+// 默认签名管理器的session保活也使用该间隔，但最长30秒，以免错过session的续期窗口
+func WithKeepAliveInterval(t time.Duration) Option {
+	return func(c *Drive) {
+		c.keepAliveInterval = t
+	}
+}
+
+// 追加调用拦截器，排在前面的拦截器在最外层
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Drive) {
+		c.Interceptors = append(c.Interceptors, interceptors...)
+	}
+}
+
+// 设置日志
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Drive) {
+		c.Logger = logger
+	}
+}
+
+// 设置监控指标收集
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Drive) {
+		c.Metrics = metrics
+	}
+}
+
+// 设置接口调用的客户端限流，同一个Drive的所有接口调用共享
+func WithApiRateLimit(limit RateLimit) Option {
+	return func(c *Drive) {
+		c.ApiRateLimit = &limit
+	}
+}
+
+// 设置上传/下载数据的客户端限流，Rate为每秒字节数，同一个Drive的所有上传/下载共享
+func WithTransferRateLimit(limit RateLimit) Option {
+	return func(c *Drive) {
+		c.TransferRateLimit = &limit
+	}
+}
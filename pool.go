@@ -0,0 +1,283 @@
+package aliyundrive
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 账号池中没有可用的账号
+var ErrNoAvailableAccount = errors.New("aliyundrive: no available account")
+
+// 多账号池
+//
+// 管理多个账号的Drive，读取时在可用的账号之间轮流选择，
+// 写入时选择剩余空间最多的账号，
+// 并根据每个账号的请求结果跟踪账号是否可用；
+// 零值可以直接使用，也可以通过NewAccountPool创建
+type AccountPool struct {
+	// 连续失败多少次之后暂停使用账号，为0则使用3
+	MaxFailures int
+	// 暂停使用账号的时间，之后会再次尝试使用，为0则使用1分钟
+	Cooldown time.Duration
+	// 剩余空间信息的缓存时间，为0则使用1分钟
+	SpaceInfoTTL time.Duration
+
+	accounts []*poolAccount
+	next     int
+	lock     sync.Mutex
+}
+
+type poolAccount struct {
+	drive          *Drive
+	failures       int
+	lastError      error
+	unhealthyUntil time.Time
+	freeSize       uint64
+	spaceInfoTime  time.Time
+}
+
+func (a *poolAccount) healthy(now time.Time) bool {
+	return a.drive != nil && !now.Before(a.unhealthyUntil)
+}
+
+// 账号的健康状态
+type AccountHealth struct {
+	Drive *Drive
+	// 当前是否可用
+	Healthy bool
+	// 连续失败的次数
+	Failures int
+	// 最近一次失败的错误
+	LastError error
+	// 暂停使用的结束时间
+	UnhealthyUntil time.Time
+	// 最近一次获取到的剩余空间
+	FreeSize uint64
+}
+
+// 创建一个空的账号池，使用Add加入账号
+func NewAccountPool() *AccountPool {
+	return new(AccountPool)
+}
+
+// 创建一个Drive并加入账号池
+//
+// opts与New相同，每个账号应该使用各自的refresh token、存储以及设备Id，
+// 账号池会追加一个拦截器用于跟踪账号的健康状态
+func (p *AccountPool) Add(ctx context.Context, opts ...Option) (*Drive, error) {
+	account := new(poolAccount)
+	opts = append(opts[:len(opts):len(opts)], WithInterceptors(p.healthInterceptor(account)))
+	drive, err := New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	account.drive = drive
+	p.accounts = append(p.accounts, account)
+	return drive, nil
+}
+
+// 账号池中所有的Drive
+func (p *AccountPool) Drives() []*Drive {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	drives := make([]*Drive, 0, len(p.accounts))
+	for _, account := range p.accounts {
+		drives = append(drives, account.drive)
+	}
+	return drives
+}
+
+// 所有账号的健康状态
+func (p *AccountPool) Health() []AccountHealth {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	result := make([]AccountHealth, 0, len(p.accounts))
+	for _, account := range p.accounts {
+		result = append(result, AccountHealth{
+			Drive:          account.drive,
+			Healthy:        account.healthy(now),
+			Failures:       account.failures,
+			LastError:      account.lastError,
+			UnhealthyUntil: account.unhealthyUntil,
+			FreeSize:       account.freeSize,
+		})
+	}
+	return result
+}
+
+// 选择一个账号用于读取，在可用的账号之间轮流选择
+//
+// 没有可用的账号时返回ErrNoAvailableAccount
+func (p *AccountPool) Read() (*Drive, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	for i := range p.accounts {
+		n := (p.next + i) % len(p.accounts)
+		if p.accounts[n].healthy(now) {
+			p.next = (n + 1) % len(p.accounts)
+			return p.accounts[n].drive, nil
+		}
+	}
+	return nil, ErrNoAvailableAccount
+}
+
+// 选择剩余空间最多的可用账号用于写入
+//
+// size为将要写入的大小，剩余空间不足的账号不会被选择，
+// 选中之后会从缓存的剩余空间中预先扣除，让并发的写入分散到不同账号；
+// 没有可用的账号时返回ErrNoAvailableAccount，空间都不足时返回ErrQuotaExhausted
+func (p *AccountPool) Write(ctx context.Context, size uint64) (*Drive, error) {
+	p.lock.Lock()
+	now := time.Now()
+	var candidates, stale []*poolAccount
+	for _, account := range p.accounts {
+		if !account.healthy(now) {
+			continue
+		}
+		candidates = append(candidates, account)
+		if now.Sub(account.spaceInfoTime) >= p.spaceInfoTTL() {
+			stale = append(stale, account)
+		}
+	}
+	p.lock.Unlock()
+	if len(candidates) == 0 {
+		return nil, ErrNoAvailableAccount
+	}
+
+	// 不持锁获取剩余空间，获取失败的账号本次不参与选择
+	failed := make(map[*poolAccount]bool)
+	for _, account := range stale {
+		if err := p.refreshSpaceInfo(ctx, account); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failed[account] = true
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var best *poolAccount
+	for _, account := range candidates {
+		if failed[account] || account.freeSize < size {
+			continue
+		}
+		if best == nil || account.freeSize > best.freeSize {
+			best = account
+		}
+	}
+	if best == nil {
+		if len(failed) == len(candidates) {
+			return nil, ErrNoAvailableAccount
+		}
+		return nil, ErrQuotaExhausted
+	}
+	best.freeSize -= size
+	return best.drive, nil
+}
+
+// 关闭账号池中所有的Drive
+func (p *AccountPool) Close() error {
+	for _, drive := range p.Drives() {
+		drive.Close()
+	}
+	return nil
+}
+
+func (p *AccountPool) refreshSpaceInfo(ctx context.Context, account *poolAccount) error {
+	resp, err := account.drive.DoGetPersonalInfoRequest(ctx, GetPersonalInfoRequest{})
+	if err != nil {
+		return err
+	}
+	var freeSize uint64
+	if info := resp.PersonalSpaceInfo; info != nil && info.TotalSize > info.UsedSize {
+		freeSize = info.TotalSize - info.UsedSize
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	account.freeSize = freeSize
+	account.spaceInfoTime = time.Now()
+	return nil
+}
+
+// 跟踪账号健康状态的拦截器
+func (p *AccountPool) healthInterceptor(account *poolAccount) Interceptor {
+	return func(ctx context.Context, call *Call, next Handler) error {
+		err := next(ctx, call)
+		p.report(account, call.Operation, err)
+		return err
+	}
+}
+
+// 记录一次请求的结果，成功则清除失败次数，连续失败达到MaxFailures次则暂停使用账号
+//
+// 只有网络错误、服务端错误、限流以及token失效才算账号的失败，
+// 文件不存在等业务错误不影响账号的健康状态
+func (p *AccountPool) report(account *poolAccount, operation string, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err == nil {
+		account.failures = 0
+		account.unhealthyUntil = time.Time{}
+		return
+	}
+	if operation != apiTokenRefresh.name && !isAccountFailure(err) {
+		return
+	}
+	account.failures++
+	account.lastError = err
+	if account.failures >= p.maxFailures() {
+		account.unhealthyUntil = time.Now().Add(p.cooldown())
+		if account.drive != nil {
+			account.drive.logger().Warn("aliyundrive: account marked unhealthy", append([]any{"user_id", account.drive.userId, "failures", account.failures}, errorAttrs(err)...)...)
+		}
+	}
+}
+
+func isAccountFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var status int
+	var errResponse *ErrorResponse
+	var ossError *OssError
+	switch {
+	case errors.As(err, &errResponse):
+		status = errResponse.StatusCode
+	case errors.As(err, &ossError):
+		status = ossError.StatusCode
+	default:
+		// 网络错误
+		return true
+	}
+	return status >= 500 || status == 401 || status == 429
+}
+
+func (p *AccountPool) maxFailures() int {
+	if p.MaxFailures <= 0 {
+		return 3
+	}
+	return p.MaxFailures
+}
+
+func (p *AccountPool) cooldown() time.Duration {
+	if p.Cooldown <= 0 {
+		return time.Minute
+	}
+	return p.Cooldown
+}
+
+func (p *AccountPool) spaceInfoTTL() time.Duration {
+	if p.SpaceInfoTTL <= 0 {
+		return time.Minute
+	}
+	return p.SpaceInfoTTL
+}
@@ -0,0 +1,157 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+	"github.com/xbugio/aliyundrive-go-sdk/aliyundrivetest"
+)
+
+// 为每个账号创建一个模拟服务并加入账号池
+func newTestPool(t *testing.T, pool *aliyundrive.AccountPool, n int) []*aliyundrivetest.Server {
+	t.Helper()
+	t.Cleanup(func() { pool.Close() })
+	var servers []*aliyundrivetest.Server
+	for i := 0; i < n; i++ {
+		srv := aliyundrivetest.NewServer()
+		t.Cleanup(srv.Close)
+		_, err := pool.Add(context.Background(),
+			aliyundrive.WithRefreshToken(srv.RefreshToken),
+			aliyundrive.WithHttpClient(srv.Client()),
+			aliyundrive.WithEndpoint(srv.Endpoint()),
+			aliyundrive.WithRetryPolicy(aliyundrive.RetryPolicy{MaxAttempts: 1}),
+		)
+		if err != nil {
+			t.Fatalf("add account %d: %v", i, err)
+		}
+		servers = append(servers, srv)
+	}
+	return servers
+}
+
+func TestAccountPoolRead(t *testing.T) {
+	// 不通过NewAccountPool创建也可以使用
+	pool := new(aliyundrive.AccountPool)
+	newTestPool(t, pool, 3)
+	drives := pool.Drives()
+
+	for i := 0; i < 6; i++ {
+		drive, err := pool.Read()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if drive != drives[i%3] {
+			t.Fatalf("read %d returned another account, want account %d", i, i%3)
+		}
+	}
+}
+
+func TestAccountPoolWrite(t *testing.T) {
+	pool := aliyundrive.NewAccountPool()
+	servers := newTestPool(t, pool, 2)
+	drives := pool.Drives()
+	ctx := context.Background()
+	servers[0].AddFile(aliyundrive.RootFileId, "a.bin", make([]byte, 10))
+
+	// 第二个账号剩余空间更多，选中后预先扣除，下一次写入选择第一个账号
+	for i, want := range []int{1, 0, 1} {
+		drive, err := pool.Write(ctx, 100)
+		if err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+		if drive != drives[want] {
+			t.Fatalf("write %d chose another account, want account %d", i, want)
+		}
+	}
+	if _, err := pool.Write(ctx, uint64(aliyundrive.TB)); !errors.Is(err, aliyundrive.ErrQuotaExhausted) {
+		t.Fatalf("write larger than free space error = %v, want ErrQuotaExhausted", err)
+	}
+	for _, health := range pool.Health() {
+		if health.FreeSize >= uint64(aliyundrive.TB)-100 {
+			t.Fatalf("account free size = %d, want the pending writes deducted", health.FreeSize)
+		}
+	}
+}
+
+func TestAccountPoolWriteNoAvailableAccount(t *testing.T) {
+	pool := aliyundrive.NewAccountPool()
+	servers := newTestPool(t, pool, 2)
+
+	// 获取剩余空间都失败时没有可以写入的账号
+	for _, srv := range servers {
+		srv.InjectFault("/v2/databox/get_personal_info", 1, 500, "InternalError")
+	}
+	if _, err := pool.Write(context.Background(), 100); !errors.Is(err, aliyundrive.ErrNoAvailableAccount) {
+		t.Fatalf("write when space info fails error = %v, want ErrNoAvailableAccount", err)
+	}
+	if _, err := pool.Write(context.Background(), 100); err != nil {
+		t.Fatalf("write after space info recovers: %v", err)
+	}
+}
+
+func TestAccountPoolCooldown(t *testing.T) {
+	pool := &aliyundrive.AccountPool{MaxFailures: 2, Cooldown: time.Millisecond * 100}
+	servers := newTestPool(t, pool, 2)
+	drives := pool.Drives()
+	ctx := context.Background()
+
+	// 文件不存在等业务错误不算账号失败
+	if _, err := drives[0].DoGetRequest(ctx, aliyundrive.GetRequest{FileId: "not-exist"}); !errors.Is(err, aliyundrive.ErrNotFound) {
+		t.Fatalf("get missing file error = %v, want ErrNotFound", err)
+	}
+	servers[0].InjectFault("/v2/file/get", 2, 500, "InternalError")
+	for i := 0; i < 2; i++ {
+		if _, err := drives[0].DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err == nil {
+			t.Fatalf("get %d with fault succeeded", i)
+		}
+	}
+	health := pool.Health()
+	if health[0].Healthy || health[0].Failures != 2 || health[0].LastError == nil || !health[1].Healthy {
+		t.Fatalf("health after 2 failures = %+v, want only the first account unhealthy", health)
+	}
+	for i := 0; i < 3; i++ {
+		if drive, err := pool.Read(); err != nil || drive != drives[1] {
+			t.Fatalf("read during cooldown error = %v, want the second account", err)
+		}
+	}
+	if drive, err := pool.Write(ctx, 100); err != nil || drive != drives[1] {
+		t.Fatalf("write during cooldown error = %v, want the second account", err)
+	}
+
+	// 冷却结束后再次使用，请求成功后清除失败次数
+	time.Sleep(pool.Cooldown)
+	seen := make(map[*aliyundrive.Drive]bool)
+	for i := 0; i < 2; i++ {
+		drive, err := pool.Read()
+		if err != nil {
+			t.Fatalf("read after cooldown: %v", err)
+		}
+		seen[drive] = true
+	}
+	if !seen[drives[0]] {
+		t.Fatal("first account was not used after cooldown")
+	}
+	if _, err := drives[0].DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get after cooldown: %v", err)
+	}
+	if health := pool.Health(); !health[0].Healthy || health[0].Failures != 0 {
+		t.Fatalf("health after recovery = %+v, want the first account healthy", health[0])
+	}
+
+	// 所有账号都不可用
+	for i, srv := range servers {
+		srv.InjectFault("/v2/file/get", 2, 500, "InternalError")
+		for j := 0; j < 2; j++ {
+			drives[i].DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
+		}
+	}
+	if _, err := pool.Read(); !errors.Is(err, aliyundrive.ErrNoAvailableAccount) {
+		t.Fatalf("read with all accounts unhealthy error = %v, want ErrNoAvailableAccount", err)
+	}
+	if _, err := pool.Write(ctx, 100); !errors.Is(err, aliyundrive.ErrNoAvailableAccount) {
+		t.Fatalf("write with all accounts unhealthy error = %v, want ErrNoAvailableAccount", err)
+	}
+}
@@ -0,0 +1,112 @@
+package aliyundrive
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// 客户端限流配置，按令牌桶算法限制速率
+//
+// 用于接口调用时一个令牌为一次请求，用于上传/下载数据时一个令牌为一个字节
+type RateLimit struct {
+	// 每秒允许的令牌数，小于等于0表示不限流
+	Rate float64
+	// 允许突发的令牌数，即令牌桶容量，小于1时按1处理
+	Burst int
+}
+
+// 令牌桶限流器，为空时不限流
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	lock   *sync.Mutex
+}
+
+func newRateLimiter(limit *RateLimit) *rateLimiter {
+	if limit == nil || limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+		lock:   new(sync.Mutex),
+	}
+}
+
+// 取得一个令牌，没有令牌时等待，ctx取消时返回ctx的错误
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// 取得n个令牌，令牌不足时等待，ctx取消时返回ctx的错误
+//
+// n可以大于令牌桶容量，此时等待到补足为止
+func (l *rateLimiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 先预定令牌，令牌数为负表示前面还有等待的请求
+	l.lock.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.lock.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// 归还预定的令牌，让后面的请求不必多等
+		l.lock.Lock()
+		l.tokens += float64(n)
+		l.lock.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// 按读取的字节数限流的数据流，读取之后等待对应数量的令牌
+type rateLimitedReadCloser struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rateLimiter
+}
+
+// 为数据流加上限流，limiter为空时原样返回
+func (l *rateLimiter) readCloser(ctx context.Context, r io.ReadCloser) io.ReadCloser {
+	if l == nil {
+		return r
+	}
+	return &rateLimitedReadCloser{ReadCloser: r, ctx: ctx, limiter: l}
+}
+
+func (r *rateLimitedReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
@@ -0,0 +1,63 @@
+package aliyundrive_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+func TestApiRateLimit(t *testing.T) {
+	_, c := newTestDrive(t, aliyundrive.WithApiRateLimit(aliyundrive.RateLimit{Rate: 20, Burst: 1}))
+	ctx := context.Background()
+
+	// 初始化已经用掉了令牌，之后每次请求间隔至少50ms
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*250 {
+		t.Fatalf("6 requests at 20/s took %v, want at least 250ms", elapsed)
+	}
+}
+
+func TestApiRateLimitCancel(t *testing.T) {
+	// 初始化刷新token和获取用户信息用掉两个令牌，之后的请求需要等待2秒
+	_, c := newTestDrive(t, aliyundrive.WithApiRateLimit(aliyundrive.RateLimit{Rate: 0.5, Burst: 2}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	start := time.Now()
+	_, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("get while limited error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("get returned after %v, want it to return on ctx cancel", elapsed)
+	}
+}
+
+func TestTransferRateLimit(t *testing.T) {
+	_, c := newTestDrive(t, aliyundrive.WithTransferRateLimit(aliyundrive.RateLimit{Rate: 8 * 1024, Burst: 1024}))
+	data := bytes.Repeat([]byte("x"), 5*1024)
+
+	// 按字节数限流，一次请求传输多个令牌桶容量的数据也需要等待
+	start := time.Now()
+	completed := uploadFile(t, c, aliyundrive.RootFileId, "a.bin", data, uint64(len(data)))
+	if elapsed := time.Since(start); elapsed < time.Millisecond*400 {
+		t.Fatalf("uploading 5KB at 8KB/s took %v, want at least 400ms", elapsed)
+	}
+
+	start = time.Now()
+	if got := downloadFile(t, c, completed.FileId); !bytes.Equal(got, data) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(data))
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*400 {
+		t.Fatalf("downloading 5KB at 8KB/s took %v, want at least 400ms", elapsed)
+	}
+}
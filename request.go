@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,36 +13,17 @@ import (
 type Array []any
 type Object map[string]any
 
-// 阿里云盘API调用出错的错误信息
-type ErrorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-}
-
-// 错误信息的描述
-func (r *ErrorResponse) Error() string {
-	return fmt.Sprintf(`{"code":"%v","message":"%v"}`, r.Code, r.Message)
-}
-
-// 判断某个error是否是云盘接口返回的PreHashMatched错误
-//
-// 由于PreHashMatched用于秒传的情况，
-// 所以遇到该错误需要走秒传后续的逻辑
-func IsPreHashMatchedError(err error) bool {
-	errResponse, ok := err.(*ErrorResponse)
-	if !ok {
-		return false
-	}
-	return errResponse.Code == "PreHashMatched"
-}
-
-func (c *Drive) toRequest(ctx context.Context, url string, params any) (*http.Request, error) {
+func (c *Drive) toRequest(ctx context.Context, a api, params any) (*http.Request, error) {
 	bodyData, err := json.Marshal(params)
 	if err != nil {
 		return nil, err
 	}
+	apiUrl, err := c.apiUrl(a)
+	if err != nil {
+		return nil, err
+	}
 	body := bytes.NewReader(bodyData)
-	request, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	request, err := http.NewRequestWithContext(ctx, "POST", apiUrl, body)
 	if err != nil {
 		return nil, err
 	}
@@ -49,18 +31,18 @@ func (c *Drive) toRequest(ctx context.Context, url string, params any) (*http.Re
 	return request, nil
 }
 
-func (c *Drive) withCredit(ctx context.Context, request *http.Request) error {
-	accessToken, err := c.tokenManager.AccessToken(ctx)
+func (c *Drive) withCredit(ctx context.Context, request *http.Request) (string, error) {
+	accessToken, err := c.TokenManager.AccessToken(ctx)
 	if err != nil {
-		return err
+		return "", err
 	}
 	request.Header.Set("Authorization", "Bearer "+accessToken)
-	request.Header.Set("X-Device-Id", c.deviceId)
-	return nil
+	request.Header.Set("X-Device-Id", c.DeviceId)
+	return accessToken, nil
 }
 
 func (c *Drive) withSignature(ctx context.Context, request *http.Request) error {
-	signature, err := c.signatureManager.Signature(ctx)
+	signature, err := c.SignatureManager.Signature(ctx)
 	if err != nil {
 		return err
 	}
@@ -68,49 +50,224 @@ func (c *Drive) withSignature(ctx context.Context, request *http.Request) error
 	return nil
 }
 
-func (c *Drive) requestWithCredit(ctx context.Context, url string, params any) ([]byte, error) {
-	request, err := c.toRequest(ctx, url, params)
-	if err != nil {
-		return nil, err
+// 带accesstoken和签名发送请求
+//
+// 若服务端以设备离线或者签名无效拒绝了签名，会让签名管理器丢弃该签名，
+// 重新创建session之后再重放一次请求；使用开放平台接口时不需要签名
+func (c *Drive) requestWithCredit(ctx context.Context, a api, params any) ([]byte, error) {
+	if c.Endpoint.Open {
+		return c.requestWithAccessToken(ctx, a, params, nil)
 	}
-	if err := c.withCredit(ctx, request); err != nil {
-		return nil, err
+	var signature string
+	prepare := func(ctx context.Context, request *http.Request) error {
+		err := c.withSignature(ctx, request)
+		signature = request.Header.Get("X-Signature")
+		return err
 	}
-	if err := c.withSignature(ctx, request); err != nil {
-		return nil, err
+	resp, err := c.requestWithAccessToken(ctx, a, params, prepare)
+	if err != nil && (errors.Is(err, ErrDeviceOffline) || errors.Is(err, ErrSignatureInvalid)) && c.invalidateSignature(signature) {
+		resp, err = c.requestWithAccessToken(ctx, a, params, prepare)
+	}
+	return resp, err
+}
+
+// 让签名管理器丢弃签名，返回是否可以重新获取
+func (c *Drive) invalidateSignature(signature string) bool {
+	signatureManager, ok := c.SignatureManager.(InvalidatableSignatureManager)
+	if !ok {
+		return false
+	}
+	signatureManager.Invalidate(signature)
+	return true
+}
+
+// 带accesstoken发送请求，prepare可以在发送前对请求做额外的处理
+//
+// 若服务端拒绝了accesstoken，会让token管理器丢弃该accesstoken，
+// 重新获取之后再重放一次请求
+func (c *Drive) requestWithAccessToken(ctx context.Context, a api, params any, prepare func(ctx context.Context, request *http.Request) error) ([]byte, error) {
+	replayed := false
+	for {
+		request, err := c.toRequest(ctx, a, params)
+		if err != nil {
+			return nil, err
+		}
+		accessToken, err := c.withCredit(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if prepare != nil {
+			if err := prepare(ctx, request); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.doRequest(a, params, request)
+		if err == nil || replayed || !errors.Is(err, ErrAccessTokenInvalid) || !c.invalidateAccessToken(accessToken) {
+			return resp, err
+		}
+		replayed = true
 	}
-	return c.doRequest(request)
 }
 
-func (c *Drive) requestWithoutCredit(ctx context.Context, url string, params any) ([]byte, error) {
-	request, err := c.toRequest(ctx, url, params)
+// 让token管理器丢弃accesstoken，返回是否可以重新获取
+func (c *Drive) invalidateAccessToken(accessToken string) bool {
+	tokenManager, ok := c.TokenManager.(InvalidatableTokenManager)
+	if !ok {
+		return false
+	}
+	tokenManager.Invalidate(accessToken)
+	return true
+}
+
+func (c *Drive) requestWithoutCredit(ctx context.Context, a api, params any) ([]byte, error) {
+	request, err := c.toRequest(ctx, a, params)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.doRequest(request)
+	resp, err := c.doRequest(a, params, request)
 	return resp, err
 }
 
-func (c *Drive) doRequest(request *http.Request) ([]byte, error) {
-	resp, err := c.HttpClient.Do(request)
+// 发送请求，遇到临时错误时按重试策略重试，每次尝试都会经过拦截器链
+//
+// 每次尝试前都会等待接口调用的限流
+func (c *Drive) doRequest(a api, params any, request *http.Request) ([]byte, error) {
+	var respData []byte
+	err := c.withRetry(request.Context(), a.name, a.retry, func(n int) (retryDecision, error) {
+		if err := c.apiLimiter.Wait(request.Context()); err != nil {
+			return retryDecision{}, err
+		}
+		attemptRequest := request
+		if n > 1 {
+			var err error
+			attemptRequest, err = rewindRequest(request)
+			if err != nil {
+				return retryDecision{}, err
+			}
+		}
+		call := &Call{
+			Operation: a.name,
+			Params:    params,
+			Attempt:   n,
+			Request:   attemptRequest,
+		}
+		decision, err := c.doCall(call, c.doRequestOnce)
+		respData = call.ResponseData
+		return decision, err
+	})
 	if err != nil {
 		return nil, err
 	}
+	return respData, nil
+}
+
+func (c *Drive) doRequestOnce(call *Call) (retryDecision, error) {
+	resp, err := c.httpClient().Do(call.Request)
+	if err != nil {
+		return retryDecision{temporary: true}, err
+	}
+	call.Response = resp
+	decision := decideByResponse(resp)
 	respData, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		return nil, err
+		decision.temporary = true
+		return decision, err
+	}
+
+	// 无返回内容的接口，比如删除
+	if resp.StatusCode == http.StatusNoContent {
+		return decision, nil
 	}
 
 	result := new(ErrorResponse)
 	err = json.Unmarshal(respData, result)
+	if err != nil {
+		if resp.StatusCode < 400 {
+			decision.temporary = true
+			return decision, fmt.Errorf("aliyundrive: invalid response body: %w", err)
+		}
+		// 网关等返回的错误可能不是json或者没有内容，按http状态码生成错误
+		result = new(ErrorResponse)
+	}
+
+	if result.Code != "" || result.Message != "" || resp.StatusCode >= 400 {
+		result.StatusCode = resp.StatusCode
+		if result.RequestId == "" {
+			result.RequestId = resp.Header.Get("X-Ca-Request-Id")
+		}
+		if result.Code == "" && result.Message == "" {
+			result.Message = resp.Status
+		}
+		if errors.Is(result, ErrTooManyRequests) {
+			decision.throttled = true
+		}
+		return decision, result
+	}
+
+	call.ResponseData = respData
+	return decision, nil
+}
+
+// 发送上传/下载数据的请求，每次尝试都会经过拦截器链
+//
+// newRequest用于创建第n次尝试的请求，返回的响应状态码一定是2xx，需要调用者关闭；
+// 上传/下载数据的限流按字节数在读取数据时进行
+func (c *Drive) doDataRequest(ctx context.Context, operation string, params any, mode retryMode, newRequest func(n int) (*http.Request, error)) (*http.Response, error) {
+	var resp *http.Response
+	err := c.withRetry(ctx, operation, mode, func(n int) (retryDecision, error) {
+		request, err := newRequest(n)
+		if err != nil {
+			return retryDecision{}, err
+		}
+		call := &Call{
+			Operation: operation,
+			Params:    params,
+			Attempt:   n,
+			Request:   request,
+		}
+		decision, err := c.doCall(call, c.doDataRequestOnce)
+		if err != nil {
+			return decision, err
+		}
+		if call.Response == nil {
+			return decision, errors.New("aliyundrive: interceptor returned no response")
+		}
+		resp = call.Response
+		return decision, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return resp, nil
+}
 
-	if result.Code != "" || result.Message != "" {
-		return nil, result
+func (c *Drive) doDataRequestOnce(call *Call) (retryDecision, error) {
+	resp, err := c.httpClient().Do(call.Request)
+	if err != nil {
+		return retryDecision{temporary: true}, err
 	}
+	call.Response = resp
+	return checkDataResponse(resp)
+}
 
-	return respData, nil
+// 发送请求使用的http客户端，未初始化时使用http.DefaultClient
+func (c *Drive) httpClient() *http.Client {
+	if c.HttpClient == nil {
+		return http.DefaultClient
+	}
+	return c.HttpClient
+}
+
+// 复制一个请求用于重试，请求体会从头开始
+func rewindRequest(request *http.Request) (*http.Request, error) {
+	result := request.Clone(request.Context())
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		result.Body = body
+	}
+	return result, nil
 }
@@ -0,0 +1,141 @@
+package aliyundrive
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 请求重试策略
+type RetryPolicy struct {
+	// 最大尝试次数（包含第一次请求），小于等于1表示不重试
+	MaxAttempts int
+	// 第一次重试前的等待时间，之后每次重试翻倍
+	MinBackoff time.Duration
+	// 最长的等待时间
+	MaxBackoff time.Duration
+}
+
+// 默认的重试策略，Drive未设置RetryPolicy时使用
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond * 500,
+	MaxBackoff:  time.Second * 10,
+}
+
+// 计算第attempt次请求失败后的等待时间
+//
+// 在指数退避的基础上加入随机抖动，
+// 若服务端通过Retry-After指定了等待时间，则至少等待该时间
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	wait := p.MinBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if wait > 0 {
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+	}
+	if wait < retryAfter {
+		wait = retryAfter
+	}
+	return wait
+}
+
+// 接口失败后允许的重试方式
+type retryMode int
+
+const (
+	// 不重试，用于请求数据无法重放的情况
+	retryNever retryMode = iota
+	// 仅在被限流（请求未被处理）时重试，用于非幂等的接口，如会轮换refresh token的刷新token
+	retryThrottled
+	// 遇到临时错误都可以重试，用于幂等或者重放安全的接口
+	retryIdempotent
+)
+
+// 单次请求失败的重试判断
+type retryDecision struct {
+	// 临时错误，幂等的请求可以重试
+	temporary bool
+	// 被限流，请求未被处理，所有可重放的请求都可以重试
+	throttled bool
+	// 服务端要求的等待时间
+	retryAfter time.Duration
+}
+
+func (d retryDecision) retryable(mode retryMode) bool {
+	switch mode {
+	case retryIdempotent:
+		return d.temporary || d.throttled
+	case retryThrottled:
+		return d.throttled
+	}
+	return false
+}
+
+// 根据http响应判断是否需要重试
+func decideByResponse(resp *http.Response) retryDecision {
+	var d retryDecision
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		d.throttled = true
+	case resp.StatusCode >= 500:
+		d.temporary = true
+	}
+	d.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	return d
+}
+
+// 解析Retry-After头，支持秒数和http时间两种格式
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Second * time.Duration(seconds)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+func (c *Drive) retryPolicy() RetryPolicy {
+	if c.RetryPolicy == nil {
+		return DefaultRetryPolicy
+	}
+	return *c.RetryPolicy
+}
+
+// 按重试策略执行attempt，attempt返回本次请求的错误以及对该错误的重试判断
+//
+// operation为调用名，用于日志
+func (c *Drive) withRetry(ctx context.Context, operation string, mode retryMode, attempt func(n int) (retryDecision, error)) error {
+	policy := c.retryPolicy()
+	for n := 1; ; n++ {
+		decision, err := attempt(n)
+		if err == nil {
+			return nil
+		}
+		if n >= policy.MaxAttempts || !decision.retryable(mode) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := policy.backoff(n, decision.retryAfter)
+		c.logger().Info("aliyundrive: retrying request", "operation", operation, "attempt", n, "wait", wait, "error", redactError(err))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package aliyundrive_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 测试用的重试策略，退避时间很短
+var testRetryPolicy = aliyundrive.RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  time.Millisecond * 10,
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	counts := map[string]*atomic.Int32{"file/get": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(testRetryPolicy), aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+
+	srv.InjectFault("/v2/file/get", 2, 500, "InternalError")
+	if _, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get with 2 faults: %v", err)
+	}
+	if got := counts["file/get"].Swap(0); got != 3 {
+		t.Fatalf("get attempted %d times, want 3", got)
+	}
+
+	srv.InjectFault("/v2/file/get", 3, 500, "InternalError")
+	_, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
+	var errResponse *aliyundrive.ErrorResponse
+	if !errors.As(err, &errResponse) || errResponse.StatusCode != 500 {
+		t.Fatalf("get with 3 faults error = %v, want 500", err)
+	}
+	if got := counts["file/get"].Load(); got != 3 {
+		t.Fatalf("get attempted %d times, want MaxAttempts 3", got)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	counts := map[string]*atomic.Int32{"file/update": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(testRetryPolicy), aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+	file := srv.AddFile(aliyundrive.RootFileId, "a.txt", nil)
+
+	// 5xx时请求可能已经被处理，不重试
+	srv.InjectFault("/v3/file/update", 1, 500, "InternalError")
+	_, err := c.DoRenameRequest(ctx, aliyundrive.RenameRequest{FileId: file.FileId, Name: "b.txt"})
+	if err == nil {
+		t.Fatal("rename with 500 succeeded, want error")
+	}
+	if got := counts["file/update"].Swap(0); got != 1 {
+		t.Fatalf("rename attempted %d times after 500, want 1", got)
+	}
+
+	// 被限流时请求未被处理，可以重试
+	srv.InjectFault("/v3/file/update", 1, 429, "TooManyRequests")
+	if _, err := c.DoRenameRequest(ctx, aliyundrive.RenameRequest{FileId: file.FileId, Name: "b.txt"}); err != nil {
+		t.Fatalf("rename with 429: %v", err)
+	}
+	if got := counts["file/update"].Load(); got != 2 {
+		t.Fatalf("rename attempted %d times after 429, want 2", got)
+	}
+}
+
+func TestRetryTokenRefreshNotReplayed(t *testing.T) {
+	counts := map[string]*atomic.Int32{"token/refresh": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(testRetryPolicy), aliyundrive.WithInterceptors(countCalls(counts)))
+	before := counts["token/refresh"].Load()
+
+	// 刷新会轮换refresh token，5xx时重放旧的refresh token只会失败
+	srv.RevokeAccessTokens()
+	srv.InjectFault("/token/refresh", 1, 500, "InternalError")
+	_, err := c.DoListRequest(context.Background(), aliyundrive.ListRequest{ParentFileId: aliyundrive.RootFileId})
+	var errResponse *aliyundrive.ErrorResponse
+	if !errors.As(err, &errResponse) || errResponse.StatusCode != 500 {
+		t.Fatalf("list with refresh fault error = %v, want the 500 from refresh", err)
+	}
+	if got := counts["token/refresh"].Load() - before; got != 1 {
+		t.Fatalf("token refresh attempted %d times, want 1", got)
+	}
+}
+
+// 第一次请求path时返回503和Retry-After头的Transport
+type retryAfterTransport struct {
+	http.RoundTripper
+	path  string
+	fired atomic.Bool
+}
+
+func (t *retryAfterTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path != t.path || t.fired.Swap(true) {
+		return t.RoundTripper.RoundTrip(r)
+	}
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Status:     "503 Service Unavailable",
+		Header:     http.Header{"Retry-After": {"1"}, "Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"code":"ServiceUnavailable","message":"retry later"}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(testRetryPolicy))
+	transport := &retryAfterTransport{RoundTripper: srv.Client().Transport, path: "/v2/file/get"}
+	c.HttpClient = &http.Client{Transport: transport}
+
+	start := time.Now()
+	if _, err := c.DoGetRequest(context.Background(), aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !transport.fired.Load() {
+		t.Fatal("Retry-After response was not sent")
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+}
+
+func TestRetryUpload(t *testing.T) {
+	data := []byte("0123456789")
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, tc := range []struct {
+		name   string
+		reader io.Reader
+	}{
+		{"bytes.Reader", bytes.NewReader(data)},
+		{"os.File", file},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			counts := map[string]*atomic.Int32{aliyundrive.OperationUpload: new(atomic.Int32)}
+			srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(testRetryPolicy), aliyundrive.WithInterceptors(countCalls(counts)))
+			ctx := context.Background()
+			created, err := c.DoCreateFileRequest(ctx, aliyundrive.CreateFileRequest{
+				Name:         "a.bin",
+				ParentFileId: aliyundrive.RootFileId,
+				Size:         uint64(len(data)),
+			})
+			if err != nil {
+				t.Fatalf("create file: %v", err)
+			}
+
+			srv.InjectFault("/upload/", 1, 500, "InternalError")
+			_, err = c.DoUploadFileRequest(ctx, aliyundrive.UploadFileRequest{Url: created.PartInfoList[0].UploadUrl, File: tc.reader})
+			if err != nil {
+				t.Fatalf("upload with 500: %v", err)
+			}
+			if got := counts[aliyundrive.OperationUpload].Load(); got != 2 {
+				t.Fatalf("upload attempted %d times, want 2", got)
+			}
+			completed, err := c.DoCompleteUploadFileRequest(ctx, aliyundrive.CompleteUploadFileRequest{FileId: created.FileId, UploadId: created.UploadId})
+			if err != nil {
+				t.Fatalf("complete upload: %v", err)
+			}
+			if content, _ := srv.Content(completed.FileId); !bytes.Equal(content, data) {
+				t.Fatalf("uploaded %q, want %q", content, data)
+			}
+		})
+	}
+
+	// 调用者的文件不会被关闭
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("seek after upload: %v", err)
+	}
+}
+
+// 请求path时返回n次非json错误响应的Transport，模拟网关返回的错误
+type gatewayErrorTransport struct {
+	http.RoundTripper
+	path   string
+	status int
+	body   string
+	n      atomic.Int32
+}
+
+func (t *gatewayErrorTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path != t.path || t.n.Add(-1) < 0 {
+		return t.RoundTripper.RoundTrip(r)
+	}
+	return &http.Response{
+		StatusCode: t.status,
+		Status:     fmt.Sprintf("%d %s", t.status, http.StatusText(t.status)),
+		Header:     http.Header{"Content-Type": {"text/html"}, "X-Ca-Request-Id": {"gateway-request"}},
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Request:    r,
+	}, nil
+}
+
+func TestRetryNonJsonError(t *testing.T) {
+	counts := map[string]*atomic.Int32{"file/get": new(atomic.Int32), "token/refresh": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithRetryPolicy(testRetryPolicy), aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+	get := func(status int, body string) error {
+		counts["file/get"].Store(0)
+		transport := &gatewayErrorTransport{RoundTripper: srv.Client().Transport, path: "/v2/file/get", status: status, body: body}
+		transport.n.Store(1)
+		c.HttpClient = &http.Client{Transport: transport}
+		_, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId})
+		return err
+	}
+
+	// 限流按状态码识别并重试
+	if err := get(http.StatusTooManyRequests, "<html>Too Many Requests</html>"); err != nil {
+		t.Fatalf("get with html 429: %v", err)
+	}
+	if got := counts["file/get"].Load(); got != 2 {
+		t.Fatalf("get attempted %d times after html 429, want 2", got)
+	}
+
+	// 4xx不是临时错误，不重试
+	err := get(http.StatusNotFound, "")
+	var errResponse *aliyundrive.ErrorResponse
+	if !errors.As(err, &errResponse) || errResponse.StatusCode != 404 || errResponse.RequestId != "gateway-request" || errResponse.Message != "404 Not Found" {
+		t.Fatalf("get with empty 404 error = %#v, want *ErrorResponse from the status", err)
+	}
+	if !errors.Is(err, aliyundrive.ErrNotFound) {
+		t.Fatalf("get with empty 404 error = %v, want ErrNotFound", err)
+	}
+	if got := counts["file/get"].Load(); got != 1 {
+		t.Fatalf("get attempted %d times after empty 404, want 1", got)
+	}
+
+	// 没有错误码的401也会刷新token后重放
+	before := counts["token/refresh"].Load()
+	if err := get(http.StatusUnauthorized, ""); err != nil {
+		t.Fatalf("get with empty 401: %v", err)
+	}
+	if got := counts["token/refresh"].Load() - before; got != 1 {
+		t.Fatalf("token refreshed %d times after empty 401, want 1", got)
+	}
+}
@@ -6,34 +6,80 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/dustinxie/ecc"
 )
 
+// 签名使用的网页版appId
+const signatureAppId = "5dde4e1bdf9e4966b387ba58f4b3fdc3"
+
+// 签名管理器
 type SignatureManager interface {
 	Signature(ctx context.Context) (string, error)
 }
 
+// 可主动失效签名的签名管理器
+//
+// 服务端以UserDeviceOffline或DeviceSessionSignatureInvalid拒绝请求时，
+// 会调用Invalidate丢弃被拒绝的签名，下次调用Signature时重新创建session
+type InvalidatableSignatureManager interface {
+	SignatureManager
+	Invalidate(signature string)
+}
+
+// 可获取当前签名nonce的签名管理器
+type NonceSignatureManager interface {
+	SignatureManager
+	// 当前签名使用的nonce，续期session时递增
+	Nonce() int
+}
+
+// session的有效期，测试时可以修改
+var sessionTTL = time.Minute * 3
+
+// session剩余有效期不足该时间时，后台保活会续期，测试时可以修改
+var sessionRenewAhead = time.Minute
+
+// 后台保活session的检查间隔，最长为sessionRenewAhead的一半，
+// 保证在续期窗口内至少检查一次
+func sessionKeepAliveInterval(t time.Duration) time.Duration {
+	return min(t, sessionRenewAhead/2)
+}
+
 type signatureManager struct {
 	drive *Drive
 
 	privateKey  *ecdsa.PrivateKey
 	expiredTime time.Time
 	signature   string
-	wg          *sync.WaitGroup
+	// 当前签名使用的nonce，每次续期session时加1
+	nonce int
+	// 保护上面的状态，生成签名期间一直持有，并发请求只会创建一次session
+	lock *sync.Mutex
+	wg   *sync.WaitGroup
 }
 
+// 创建一个签名管理器
+//
+// 签名管理器在第一次获取签名时生成密钥并创建设备session，session过期后重新创建，
+// 调用KeepAlive后会在后台定时续期session，续期时nonce加1并用原来的密钥重新签名
 func NewSignatureManager(drive *Drive) *signatureManager {
 	m := &signatureManager{
 		drive: drive,
+		lock:  new(sync.Mutex),
 		wg:    new(sync.WaitGroup),
 	}
 	return m
 }
 
 func (m *signatureManager) Signature(ctx context.Context) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	if m.expiredTime.Before(time.Now()) {
 		err := m.genSignature(ctx)
 		if err != nil {
@@ -43,6 +89,87 @@ func (m *signatureManager) Signature(ctx context.Context) (string, error) {
 	return m.signature, nil
 }
 
+// 当前签名使用的nonce
+func (m *signatureManager) Nonce() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.nonce
+}
+
+// 丢弃被服务端拒绝的签名
+//
+// 只有当前的签名与被拒绝的一致时才会丢弃，避免并发请求同时被拒绝时重复创建session
+func (m *signatureManager) Invalidate(signature string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.signature == signature {
+		m.expiredTime = time.Unix(0, 0)
+	}
+}
+
+// 开始在后台保活session
+//
+// ctx：当ctx Done事件到来之后，则结束保活
+//
+// t：检查session有效期的时间间隔，超过sessionRenewAhead的一半时按其一半处理
+func (m *signatureManager) KeepAlive(ctx context.Context, t time.Duration) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(sessionKeepAliveInterval(t))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			m.renew(ctx)
+		}
+	}()
+}
+
+// 当KeepAlive的ctx Done事件到来，调用WaitStop，等待保活任务完全终止
+func (m *signatureManager) WaitStop() {
+	m.wg.Wait()
+}
+
+// session即将过期时续期，还没有session或者已经过期则不处理，等下次获取签名时重新创建
+//
+// 续期使用下一个nonce的签名，成功后之后的请求都使用新的签名
+func (m *signatureManager) renew(ctx context.Context) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	if m.signature == "" || m.expiredTime.Before(now) || m.expiredTime.Sub(now) > sessionRenewAhead {
+		return
+	}
+	nonce := m.nonce + 1
+	signature, err := m.sign(m.privateKey, nonce)
+	if err != nil {
+		m.drive.logger().Warn("aliyundrive: sign device session failed", "error", err)
+		return
+	}
+	_, err = m.drive.DoRenewSessionRequest(ctx, RenewSessionRequest{
+		Signature: signature,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		m.drive.logger().Warn("aliyundrive: renew device session failed", append([]any{"device_id", m.drive.DeviceId}, errorAttrs(err)...)...)
+		if errors.Is(err, ErrDeviceOffline) || errors.Is(err, ErrSignatureInvalid) {
+			m.expiredTime = time.Unix(0, 0)
+		}
+		return
+	}
+	m.signature = signature
+	m.nonce = nonce
+	m.expiredTime = now.Add(sessionTTL)
+	m.drive.logger().Debug("aliyundrive: device session renewed", "device_id", m.drive.DeviceId, "nonce", nonce)
+}
+
 func (m *signatureManager) genSignature(ctx context.Context) error {
 
 	// 生成privateKey
@@ -54,37 +181,48 @@ func (m *signatureManager) genSignature(ctx context.Context) error {
 	// 获取publicKey
 	publicKeyString := m.getPublicKeyString(privateKey)
 
-	// 用key去生成signature
-	code := "5dde4e1bdf9e4966b387ba58f4b3fdc3:" + m.drive.deviceId + ":" + m.drive.userId + ":0"
-	hasher := sha256.New()
-	hasher.Write([]byte(code))
-	sum := hasher.Sum(nil)
-
-	signatureData, err := ecc.SignBytes(privateKey, sum, ecc.RecID|ecc.LowerS)
+	// 用key去生成signature，新session的nonce从0开始
+	signature, err := m.sign(privateKey, 0)
 	if err != nil {
 		return err
 	}
-	signature := hex.EncodeToString(signatureData)
 
 	// 提交key到阿里云
 	now := time.Now()
 	_, err = m.drive.DoCreateSessionRequest(ctx, CreateSessionRequest{
-		DeviceName: "Chrome浏览器",
-		ModelName:  "Mac OS网页版",
+		DeviceName: m.drive.DeviceName,
+		ModelName:  m.drive.ModelName,
 		PubKey:     publicKeyString,
 		Signature:  signature,
 	})
 	if err != nil {
+		m.drive.logger().Warn("aliyundrive: create device session failed", append([]any{"device_id", m.drive.DeviceId}, errorAttrs(err)...)...)
 		return err
 	}
+	m.drive.logger().Info("aliyundrive: device session created", "device_id", m.drive.DeviceId)
 
 	// 没问题则更新存储
 	m.signature = signature
 	m.privateKey = privateKey
-	m.expiredTime = now.Add(time.Minute * 3)
+	m.nonce = 0
+	m.expiredTime = now.Add(sessionTTL)
 	return nil
 }
 
+// 用privateKey对appId:deviceId:userId:nonce签名
+func (m *signatureManager) sign(privateKey *ecdsa.PrivateKey, nonce int) (string, error) {
+	code := signatureAppId + ":" + m.drive.DeviceId + ":" + m.drive.userId + ":" + strconv.Itoa(nonce)
+	hasher := sha256.New()
+	hasher.Write([]byte(code))
+	sum := hasher.Sum(nil)
+
+	signatureData, err := ecc.SignBytes(privateKey, sum, ecc.RecID|ecc.LowerS)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signatureData), nil
+}
+
 func (m *signatureManager) getPublicKeyString(privateKey *ecdsa.PrivateKey) string {
 	xData := privateKey.PublicKey.X.Bytes()
 	yData := privateKey.PublicKey.Y.Bytes()
@@ -0,0 +1,121 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+)
+
+// 并发执行n次fn并等待全部结束
+func parallel(n int, fn func()) {
+	wg := new(sync.WaitGroup)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSignatureConcurrent(t *testing.T) {
+	counts := map[string]*atomic.Int32{"session/create": new(atomic.Int32)}
+	_, c := newTestDrive(t, aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+
+	signatures := make(chan string, 20)
+	parallel(20, func() {
+		signature, err := c.SignatureManager.Signature(ctx)
+		if err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		signatures <- signature
+	})
+	close(signatures)
+	first := <-signatures
+	for signature := range signatures {
+		if signature != first {
+			t.Fatalf("concurrent Signature returned different signatures %s and %s", first, signature)
+		}
+	}
+	if got := counts["session/create"].Load(); got != 1 {
+		t.Fatalf("session created %d times, want 1", got)
+	}
+}
+
+func TestSignatureConcurrentRequests(t *testing.T) {
+	counts := map[string]*atomic.Int32{"session/create": new(atomic.Int32)}
+	srv, c := newTestDrive(t, aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+
+	request := func() {
+		if _, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+			t.Errorf("get: %v", err)
+		}
+	}
+	parallel(20, request)
+	if got := counts["session/create"].Load(); got != 1 {
+		t.Fatalf("session created %d times by concurrent requests, want 1", got)
+	}
+
+	// 并发请求同时被拒绝时，只丢弃一次签名并重新创建一次session
+	srv.RevokeDeviceSessions()
+	parallel(20, request)
+	if got := counts["session/create"].Load(); got != 2 {
+		t.Fatalf("session created %d times after revoking, want 2", got)
+	}
+}
+
+func TestSignatureNonce(t *testing.T) {
+	_, c := newTestDrive(t)
+	if _, err := c.DoGetRequest(context.Background(), aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if nonce, ok := c.SignatureNonce(); !ok || nonce != 0 {
+		t.Fatalf("SignatureNonce = %d, %v, want 0 for a new session", nonce, ok)
+	}
+	if _, ok := c.SignatureManager.(aliyundrive.NonceSignatureManager); !ok {
+		t.Fatal("default signature manager does not implement NonceSignatureManager")
+	}
+}
+
+func TestSignatureRenew(t *testing.T) {
+	// 创建session后200ms进入续期窗口，续期一次之后要再过200ms才会再次续期
+	t.Cleanup(aliyundrive.SetSessionTTL(time.Second*2, time.Millisecond*1800))
+	counts := map[string]*atomic.Int32{"session/create": new(atomic.Int32), "session/renew": new(atomic.Int32)}
+	_, c := newTestDrive(t, aliyundrive.WithKeepAliveInterval(time.Millisecond*10), aliyundrive.WithInterceptors(countCalls(counts)))
+	ctx := context.Background()
+
+	if _, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if nonce, _ := c.SignatureNonce(); nonce != 0 {
+			if nonce != 1 {
+				t.Fatalf("nonce = %d after the first renewal, want 1", nonce)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session was not renewed in the background")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	// 服务端只接受续期后的新签名
+	if _, err := c.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get after renewal: %v", err)
+	}
+	if got := counts["session/create"].Load(); got != 1 {
+		t.Fatalf("session created %d times, want 1 with renewal", got)
+	}
+	if got := counts["session/renew"].Load(); got == 0 {
+		t.Fatal("renew session was not called")
+	}
+}
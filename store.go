@@ -0,0 +1,123 @@
+package aliyundrive
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// refresh token的持久化存储
+//
+// 每次refresh接口都会轮换出新的refresh token，旧的随之失效，
+// 需要保存下来以便程序重启之后继续使用
+type RefreshTokenStore interface {
+	// 读取保存的refresh token，没有保存过则返回空字符串
+	Load(ctx context.Context) (string, error)
+	// 保存新的refresh token
+	Save(ctx context.Context, refreshToken string) error
+}
+
+// 保存轮换出的refresh token，返回是否保存成功
+//
+// 此时旧的refresh token已经失效，新的accesstoken仍然可用，
+// 所以保存失败只记录日志，由调用者在下次获取accesstoken时重试
+func saveRefreshToken(ctx context.Context, logger *slog.Logger, store RefreshTokenStore, refreshToken string) bool {
+	if err := store.Save(ctx, refreshToken); err != nil {
+		logger.Error("aliyundrive: save refresh token failed", "refresh_token", redact(refreshToken), "error", err)
+		return false
+	}
+	return true
+}
+
+type memoryRefreshTokenStore struct {
+	refreshToken string
+	lock         *sync.Mutex
+}
+
+// 创建一个内存refresh token存储
+//
+// 内存存储不能跨进程保存，一般用于测试或者由调用者自行读取后持久化的场景
+//
+// refreshToken：初始的refresh token，可以为空
+func NewMemoryRefreshTokenStore(refreshToken string) *memoryRefreshTokenStore {
+	return &memoryRefreshTokenStore{
+		refreshToken: refreshToken,
+		lock:         new(sync.Mutex),
+	}
+}
+
+func (s *memoryRefreshTokenStore) Load(ctx context.Context) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.refreshToken, nil
+}
+
+func (s *memoryRefreshTokenStore) Save(ctx context.Context, refreshToken string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.refreshToken = refreshToken
+	return nil
+}
+
+type fileRefreshTokenStore struct {
+	path string
+	lock *sync.Mutex
+}
+
+// 创建一个文件refresh token存储
+//
+// refresh token以纯文本保存在path文件中（权限0600），
+// 保存时先写临时文件再重命名，避免写到一半时程序退出导致文件损坏
+func NewFileRefreshTokenStore(path string) *fileRefreshTokenStore {
+	return &fileRefreshTokenStore{
+		path: path,
+		lock: new(sync.Mutex),
+	}
+}
+
+func (s *fileRefreshTokenStore) Load(ctx context.Context) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *fileRefreshTokenStore) Save(ctx context.Context, refreshToken string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+	defer os.Remove(tempPath)
+
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.WriteString(refreshToken); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, s.path)
+}
@@ -0,0 +1,130 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+	"github.com/xbugio/aliyundrive-go-sdk/aliyundrivetest"
+)
+
+// 前failures次保存都失败的refresh token存储
+type flakyRefreshTokenStore struct {
+	aliyundrive.RefreshTokenStore
+	failures atomic.Int32
+}
+
+func (s *flakyRefreshTokenStore) Save(ctx context.Context, refreshToken string) error {
+	if s.failures.Add(-1) >= 0 {
+		return errors.New("disk full")
+	}
+	return s.RefreshTokenStore.Save(ctx, refreshToken)
+}
+
+func TestFileRefreshTokenStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "refresh_token")
+	store := aliyundrive.NewFileRefreshTokenStore(path)
+
+	if refreshToken, err := store.Load(ctx); err != nil || refreshToken != "" {
+		t.Fatalf("load before save = %q, %v, want empty", refreshToken, err)
+	}
+	for _, refreshToken := range []string{"first-token", "second"} {
+		if err := store.Save(ctx, refreshToken); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		if got, err := store.Load(ctx); err != nil || got != refreshToken {
+			t.Fatalf("load = %q, %v, want %q", got, err, refreshToken)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Fatalf("refresh token file mode = %v, want 0600", mode)
+	}
+	// 通过临时文件重命名替换，不会留下临时文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("store dir has %d entries, want only the refresh token file", len(entries))
+	}
+}
+
+func TestStoredRefreshTokenManager(t *testing.T) {
+	srv := aliyundrivetest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "refresh_token")
+
+	c, err := srv.NewDrive(aliyundrive.WithRefreshTokenStore(aliyundrive.NewFileRefreshTokenStore(path)))
+	if err != nil {
+		t.Fatalf("new drive: %v", err)
+	}
+	c.Close()
+	if data, _ := os.ReadFile(path); string(data) != srv.RefreshToken {
+		t.Fatalf("stored refresh token = %q, want the rotated %q", data, srv.RefreshToken)
+	}
+
+	// 重启后使用保存的refresh token，而不是已经失效的初始refresh token
+	c, err = srv.NewDrive(
+		aliyundrive.WithRefreshToken("stale-refresh-token"),
+		aliyundrive.WithRefreshTokenStore(aliyundrive.NewFileRefreshTokenStore(path)),
+	)
+	if err != nil {
+		t.Fatalf("new drive after restart: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.DoGetRequest(context.Background(), aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get after restart: %v", err)
+	}
+}
+
+func TestRefreshTokenSaveRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		newDrive func(srv *aliyundrivetest.Server, store aliyundrive.RefreshTokenStore) (*aliyundrive.Drive, error)
+	}{
+		{"refresh token", func(srv *aliyundrivetest.Server, store aliyundrive.RefreshTokenStore) (*aliyundrive.Drive, error) {
+			return srv.NewDrive(aliyundrive.WithRefreshTokenStore(store))
+		}},
+		{"oauth", func(srv *aliyundrivetest.Server, store aliyundrive.RefreshTokenStore) (*aliyundrive.Drive, error) {
+			config := srv.OAuthConfig()
+			config.Code = srv.IssueOAuthCode()
+			return newOAuthDrive(srv, config, aliyundrive.WithRefreshTokenStore(store))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := aliyundrivetest.NewServer()
+			defer srv.Close()
+			store := &flakyRefreshTokenStore{RefreshTokenStore: aliyundrive.NewMemoryRefreshTokenStore("")}
+			store.failures.Store(1)
+
+			// 保存失败时accesstoken仍然可用
+			c, err := tc.newDrive(srv, store)
+			if err != nil {
+				t.Fatalf("new drive with failing store: %v", err)
+			}
+			defer c.Close()
+			if saved, _ := store.Load(context.Background()); saved != "" {
+				t.Fatalf("stored refresh token = %q after failed save, want empty", saved)
+			}
+
+			// 下次获取accesstoken时重试保存
+			if _, err := c.DoGetRequest(context.Background(), aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if saved, _ := store.Load(context.Background()); saved != srv.RefreshToken {
+				t.Fatalf("stored refresh token = %q, want the rotated %q", saved, srv.RefreshToken)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package aliyundrive
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -11,6 +12,15 @@ type TokenManager interface {
 	AccessToken(ctx context.Context) (string, error)
 }
 
+// 可主动失效accesstoken的Token管理器
+//
+// 服务端以AccessTokenInvalid或AccessTokenExpired拒绝请求时，
+// 会调用Invalidate丢弃被拒绝的accesstoken，下次调用AccessToken时强制重新获取
+type InvalidatableTokenManager interface {
+	TokenManager
+	Invalidate(accessToken string)
+}
+
 type staticTokenManager struct {
 	accessToken string
 }
@@ -33,7 +43,10 @@ type refreshTokenManager struct {
 	refreshToken          string
 	accessToken           string
 	accessTokenExpireTime time.Time
+	store                 RefreshTokenStore
 	lock                  *sync.Mutex
+	// 轮换出的refresh token尚未保存成功，下次获取accesstoken时重试保存
+	unsaved bool
 }
 
 // 创建一个RefreshToken管理器
@@ -51,9 +64,29 @@ func NewRefreshTokenManager(drive *Drive, refreshToken string) *refreshTokenMana
 	}
 }
 
+// 创建一个带持久化存储的RefreshToken管理器
+//
+// 优先使用store中保存的refresh token，store中没有才使用refreshToken，
+// 之后每次refresh轮换出新的refresh token都会保存到store中
+func NewStoredRefreshTokenManager(ctx context.Context, drive *Drive, refreshToken string, store RefreshTokenStore) (*refreshTokenManager, error) {
+	storedRefreshToken, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if storedRefreshToken != "" {
+		refreshToken = storedRefreshToken
+	}
+	m := NewRefreshTokenManager(drive, refreshToken)
+	m.store = store
+	return m, nil
+}
+
 func (m *refreshTokenManager) AccessToken(ctx context.Context) (string, error) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	if m.unsaved {
+		m.unsaved = !saveRefreshToken(ctx, m.drive.logger(), m.store, m.refreshToken)
+	}
 
 	now := time.Now()
 	if now.Before(m.accessTokenExpireTime) {
@@ -67,23 +100,44 @@ func (m *refreshTokenManager) AccessToken(ctx context.Context) (string, error) {
 	return m.accessToken, nil
 }
 
+// 丢弃被服务端拒绝的accesstoken
+//
+// 只有当前缓存的accesstoken与被拒绝的一致时才会丢弃，
+// 避免并发请求同时被拒绝时重复refresh
+func (m *refreshTokenManager) Invalidate(accessToken string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.accessToken == accessToken {
+		m.accessTokenExpireTime = time.Unix(0, 0)
+	}
+}
+
 func (m *refreshTokenManager) refresh(ctx context.Context) error {
 	now := time.Now()
+	logger := m.drive.logger()
 	resp, err := m.drive.DoRefreshTokenRequest(ctx, RefreshTokenRequest{
 		RefreshToken: m.refreshToken,
 	})
+	m.drive.metrics().ObserveTokenRefresh(err)
 	if err != nil {
+		logger.Error("aliyundrive: refresh access token failed", append([]any{"refresh_token", redact(m.refreshToken)}, errorAttrs(err)...)...)
 		return err
 	}
 	m.refreshToken = resp.RefreshToken
 	m.accessToken = resp.AccessToken
 	m.accessTokenExpireTime = now.Add(time.Second * time.Duration(resp.ExpiresIn-60))
+	logger.Info("aliyundrive: access token refreshed", "refresh_token", redact(resp.RefreshToken), "expires_in", resp.ExpiresIn)
+	if m.store != nil {
+		m.unsaved = !saveRefreshToken(ctx, logger, m.store, resp.RefreshToken)
+	}
 	return nil
 }
 
 type keepAliveTokenManager struct {
 	tokenManager TokenManager
 	wg           *sync.WaitGroup
+	// 保活失败时记录日志，可为空
+	logger *slog.Logger
 }
 
 // 创建一个保活Token管理器
@@ -103,6 +157,13 @@ func (m *keepAliveTokenManager) AccessToken(ctx context.Context) (string, error)
 	return m.tokenManager.AccessToken(ctx)
 }
 
+// 丢弃被服务端拒绝的accesstoken，实际需要保活的token管理器不支持则忽略
+func (m *keepAliveTokenManager) Invalidate(accessToken string) {
+	if tokenManager, ok := m.tokenManager.(InvalidatableTokenManager); ok {
+		tokenManager.Invalidate(accessToken)
+	}
+}
+
 // 开始保活
 //
 // ctx：当ctx Done事件到来之后，则结束保活
@@ -120,7 +181,10 @@ func (m *keepAliveTokenManager) KeepAlive(ctx context.Context, t time.Duration)
 			case <-ctx.Done():
 				break keepaliveLoop
 			}
-			m.AccessToken(ctx)
+			_, err := m.AccessToken(ctx)
+			if err != nil && ctx.Err() == nil && m.logger != nil {
+				m.logger.Warn("aliyundrive: keep alive access token failed", errorAttrs(err)...)
+			}
 		}
 		ticker.Stop()
 	}()
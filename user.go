@@ -3,6 +3,7 @@ package aliyundrive
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 )
 
 type GetUserInfoRequest struct {
@@ -23,7 +24,11 @@ type GetUserInfoResponse struct {
 	UserName       string `json:"user_name"`
 	Description    string `json:"description"`
 	DefaultDriveID string `json:"default_drive_id"`
-	UserData       struct {
+	// 资源库网盘Id，新账号才有
+	ResourceDriveId string `json:"resource_drive_id"`
+	// 备份盘网盘Id，新账号才有
+	BackupDriveId string `json:"backup_drive_id"`
+	UserData      struct {
 	} `json:"user_data"`
 	DenyChangePasswordBySelf    bool        `json:"deny_change_password_by_self"`
 	NeedChangePasswordNextLogin bool        `json:"need_change_password_next_login"`
@@ -36,14 +41,7 @@ type GetUserInfoResponse struct {
 
 // 获取用户信息接口
 func (c *Drive) DoGetUserInfoRequest(ctx context.Context, request GetUserInfoRequest) (*GetUserInfoResponse, error) {
-	httpRequest, err := c.toRequest(ctx, "https://api.aliyundrive.com/v2/user/get", Object{})
-	if err != nil {
-		return nil, err
-	}
-	if err := c.withCredit(ctx, httpRequest); err != nil {
-		return nil, err
-	}
-	resp, err := c.doRequest(httpRequest)
+	resp, err := c.requestWithAccessToken(ctx, apiUserGet, Object{}, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +66,7 @@ type RefreshTokenResponse struct {
 
 // 刷新accesstoken接口，该接口只需要refresh token，不需要accesstoken
 func (c *Drive) DoRefreshTokenRequest(ctx context.Context, request RefreshTokenRequest) (*RefreshTokenResponse, error) {
-	resp, err := c.requestWithoutCredit(ctx, "https://api.aliyundrive.com/token/refresh", request)
+	resp, err := c.requestWithoutCredit(ctx, apiTokenRefresh, request)
 	if err != nil {
 		return nil, err
 	}
@@ -95,15 +93,10 @@ type CreateSessionResponse struct {
 
 // 发布publicKey到服务器，该接口不需要signature，但需要accesstoken
 func (c *Drive) DoCreateSessionRequest(ctx context.Context, request CreateSessionRequest) (*CreateSessionResponse, error) {
-	httpRequest, err := c.toRequest(ctx, "https://api.aliyundrive.com/users/v1/users/device/create_session", request)
-	if err != nil {
-		return nil, err
-	}
-	if err := c.withCredit(ctx, httpRequest); err != nil {
-		return nil, err
-	}
-	httpRequest.Header.Set("X-Signature", request.Signature)
-	resp, err := c.doRequest(httpRequest)
+	resp, err := c.requestWithAccessToken(ctx, apiCreateSession, request, func(ctx context.Context, httpRequest *http.Request) error {
+		httpRequest.Header.Set("X-Signature", request.Signature)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +110,8 @@ func (c *Drive) DoCreateSessionRequest(ctx context.Context, request CreateSessio
 }
 
 type RenewSessionRequest struct {
+	// 续期使用的签名，一般为nonce加1之后的签名，为空则使用签名管理器当前的签名
+	Signature string `json:"-"`
 }
 
 type RenewSessionResponse struct {
@@ -124,19 +119,119 @@ type RenewSessionResponse struct {
 
 // 刷新session
 func (c *Drive) DoRenewSessionRequest(ctx context.Context, request RenewSessionRequest) (*RenewSessionResponse, error) {
-	httpRequest, err := c.toRequest(ctx, "https://api.aliyundrive.com/users/v1/users/device/renew_session", Object{})
+	prepare := c.withSignature
+	if request.Signature != "" {
+		prepare = func(ctx context.Context, httpRequest *http.Request) error {
+			httpRequest.Header.Set("X-Signature", request.Signature)
+			return nil
+		}
+	}
+	resp, err := c.requestWithAccessToken(ctx, apiRenewSession, Object{}, prepare)
 	if err != nil {
 		return nil, err
 	}
-	if err := c.withCredit(ctx, httpRequest); err != nil {
+
+	result := new(RenewSessionResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
 		return nil, err
 	}
-	resp, err := c.doRequest(httpRequest)
+	return result, nil
+}
+
+type ListDevicesRequest struct {
+}
+
+// 登录设备信息
+type DeviceInfo struct {
+	DeviceId   string `json:"deviceId"`
+	DeviceName string `json:"deviceName"`
+	ModelName  string `json:"modelName"`
+	City       string `json:"city"`
+	LoginTime  string `json:"loginTime"`
+}
+
+type ListDevicesResponse struct {
+	Result  []DeviceInfo `json:"result"`
+	Success bool         `json:"success"`
+}
+
+// 获取账号当前在线的登录设备列表
+func (c *Drive) DoListDevicesRequest(ctx context.Context, request ListDevicesRequest) (*ListDevicesResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiDeviceList, Object{})
 	if err != nil {
 		return nil, err
 	}
 
-	result := new(RenewSessionResponse)
+	result := new(ListDevicesResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type LogoutDeviceRequest struct {
+	// 设备Id，必须
+	DeviceId string `json:"deviceId"`
+}
+
+type LogoutDeviceResponse struct {
+	Result  bool `json:"result"`
+	Success bool `json:"success"`
+}
+
+// 让登录设备下线，该设备之后的请求会返回UserDeviceOffline错误
+func (c *Drive) DoLogoutDeviceRequest(ctx context.Context, request LogoutDeviceRequest) (*LogoutDeviceResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiDeviceLogout, request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(LogoutDeviceResponse)
+	err = json.Unmarshal(resp, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type ListDrivesRequest struct {
+	// 最大返回条目，可选
+	Limit int `json:"limit,omitempty"`
+	// 分页标记，可选
+	NextMarker string `json:"marker,omitempty"`
+}
+
+// 网盘信息
+type DriveInfo struct {
+	DriveId   string `json:"drive_id"`
+	DriveName string `json:"drive_name"`
+	DriveType string `json:"drive_type"`
+	// 网盘类别，如backup（备份盘）、resource（资源库）
+	Category  string `json:"category"`
+	Owner     string `json:"owner"`
+	Status    string `json:"status"`
+	TotalSize uint64 `json:"total_size"`
+	UsedSize  uint64 `json:"used_size"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ListDrivesResponse struct {
+	// 用户的网盘列表
+	Items []*DriveInfo `json:"items"`
+	// 下一页分页标记，为空则表示没有更多数据了
+	NextMarker string `json:"next_marker"`
+}
+
+// 获取用户的网盘列表接口
+func (c *Drive) DoListDrivesRequest(ctx context.Context, request ListDrivesRequest) (*ListDrivesResponse, error) {
+	resp, err := c.requestWithCredit(ctx, apiDriveList, request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(ListDrivesResponse)
 	err = json.Unmarshal(resp, result)
 	if err != nil {
 		return nil, err
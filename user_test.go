@@ -0,0 +1,93 @@
+package aliyundrive_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xbugio/aliyundrive-go-sdk"
+	"github.com/xbugio/aliyundrive-go-sdk/aliyundrivetest"
+)
+
+func TestDevices(t *testing.T) {
+	srv, a := newTestDrive(t, aliyundrive.WithDeviceId("device-a"), aliyundrive.WithDeviceName("agent-a", "linux"))
+	ctx := context.Background()
+	counts := map[string]*atomic.Int32{"session/create": new(atomic.Int32)}
+	b, err := srv.NewDrive(
+		aliyundrive.WithDeviceId("device-b"),
+		aliyundrive.WithDeviceName("agent-b", "linux"),
+		aliyundrive.WithInterceptors(countCalls(counts)),
+	)
+	if err != nil {
+		t.Fatalf("new drive b: %v", err)
+	}
+	defer b.Close()
+	// 使用New以外方式创建的Drive也可以设置设备Id
+	c := &aliyundrive.Drive{
+		RefreshToken: srv.RefreshToken,
+		HttpClient:   srv.Client(),
+		Endpoint:     srv.Endpoint(),
+		DeviceId:     "device-c",
+	}
+	if err := c.Init(); err != nil {
+		t.Fatalf("init drive c: %v", err)
+	}
+	defer c.Close()
+
+	for _, drive := range []*aliyundrive.Drive{a, b, c} {
+		if _, err := drive.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+			t.Fatalf("get from %s: %v", drive.DeviceId, err)
+		}
+	}
+	devices, err := a.DoListDevicesRequest(ctx, aliyundrive.ListDevicesRequest{})
+	if err != nil {
+		t.Fatalf("list devices: %v", err)
+	}
+	want := []aliyundrive.DeviceInfo{
+		{DeviceId: "device-a", DeviceName: "agent-a", ModelName: "linux"},
+		{DeviceId: "device-b", DeviceName: "agent-b", ModelName: "linux"},
+		{DeviceId: "device-c", DeviceName: aliyundrive.DefaultDeviceName, ModelName: aliyundrive.DefaultModelName},
+	}
+	if len(devices.Result) != len(want) {
+		t.Fatalf("listed %d devices, want %d: %+v", len(devices.Result), len(want), devices.Result)
+	}
+	for i, device := range devices.Result {
+		if device.DeviceId != want[i].DeviceId || device.DeviceName != want[i].DeviceName || device.ModelName != want[i].ModelName {
+			t.Fatalf("device %d = %+v, want %+v", i, device, want[i])
+		}
+	}
+
+	// 下线的设备在下次请求时重新创建session
+	if _, err := a.DoLogoutDeviceRequest(ctx, aliyundrive.LogoutDeviceRequest{DeviceId: "device-b"}); err != nil {
+		t.Fatalf("logout device b: %v", err)
+	}
+	if _, err := b.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get from device b after logout: %v", err)
+	}
+	if got := counts["session/create"].Load(); got != 2 {
+		t.Fatalf("device b created %d sessions, want 2", got)
+	}
+	if _, err := a.DoGetRequest(ctx, aliyundrive.GetRequest{FileId: aliyundrive.RootFileId}); err != nil {
+		t.Fatalf("get from device a after logging out b: %v", err)
+	}
+}
+
+func TestListDrives(t *testing.T) {
+	srv, c := newTestDrive(t)
+	srv.AddDriveFile(aliyundrivetest.ResourceDriveId, aliyundrive.RootFileId, "a.txt", []byte("hello"))
+
+	drives, err := c.DoListDrivesRequest(context.Background(), aliyundrive.ListDrivesRequest{})
+	if err != nil {
+		t.Fatalf("list drives: %v", err)
+	}
+	categories := make(map[string]*aliyundrive.DriveInfo)
+	for _, drive := range drives.Items {
+		categories[drive.Category] = drive
+	}
+	if drive := categories["backup"]; drive == nil || drive.DriveId != c.DriveId() {
+		t.Fatalf("backup drive = %+v, want the default drive %s", drive, c.DriveId())
+	}
+	if drive := categories["resource"]; drive == nil || drive.DriveId != c.ResourceDriveId() || drive.UsedSize != 5 {
+		t.Fatalf("resource drive = %+v, want %s using 5 bytes", drive, c.ResourceDriveId())
+	}
+}